@@ -0,0 +1,172 @@
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"go.uber.org/zap"
+	"net/http"
+	"personal-homepage-service/config"
+	"personal-homepage-service/core"
+	"personal-homepage-service/core/repositories"
+	"personal-homepage-service/metrics"
+	"strconv"
+	"time"
+)
+
+type Server struct {
+	logger       *zap.Logger
+	orchestrator *core.Orchestrator
+	runs         *repositories.RunRepository
+	token        string
+	http         *http.Server
+}
+
+func NewServer(logger *zap.Logger, cfg config.Config, orchestrator *core.Orchestrator, runs *repositories.RunRepository) *Server {
+	s := &Server{
+		logger:       logger,
+		orchestrator: orchestrator,
+		runs:         runs,
+		token:        cfg.Admin.Token,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /workers", s.listWorkers)
+	mux.HandleFunc("POST /workers/{name}/run", s.runWorker)
+	mux.HandleFunc("POST /workers/{name}/pause", s.pauseWorker)
+	mux.HandleFunc("POST /workers/{name}/resume", s.resumeWorker)
+	mux.HandleFunc("GET /runs", s.listRuns)
+
+	// Metrics are served without the admin token so scrapers need no secret.
+	root := http.NewServeMux()
+	root.Handle("GET /metrics", metrics.Handler())
+	root.Handle("/", s.authorize(mux))
+
+	s.http = &http.Server{
+		Addr:              cfg.Admin.Addr,
+		Handler:           root,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	return s
+}
+
+// Start serves in the background. Errors after startup are logged.
+func (s *Server) Start() {
+	go func() {
+		s.logger.Info("Admin server listening", zap.String("addr", s.http.Addr))
+		if err := s.http.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("Admin server stopped", zap.Error(err))
+		}
+	}()
+}
+
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.http.Shutdown(ctx)
+}
+
+func (s *Server) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			expected := "Bearer " + s.token
+			if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(expected)) != 1 {
+				writeError(w, http.StatusUnauthorized, errors.New("unauthorized"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listWorkers(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, s.orchestrator.Workers())
+}
+
+// runWorker triggers a worker immediately. A shipment_id query parameter
+// (or the generic target parameter) limits the run to a single item.
+func (s *Server) runWorker(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	target := r.URL.Query().Get("target")
+	if target == "" {
+		target = r.URL.Query().Get("shipment_id")
+	}
+
+	var err error
+	if target != "" {
+		err = s.orchestrator.TriggerTarget(name, target)
+	} else {
+		err = s.orchestrator.Trigger(name)
+	}
+
+	if err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+
+	s.logger.Info("Worker triggered manually",
+		zap.String("worker", name),
+		zap.String("target", target),
+	)
+	writeJSON(w, http.StatusAccepted, map[string]string{"status": "triggered"})
+}
+
+func (s *Server) pauseWorker(w http.ResponseWriter, r *http.Request) {
+	if err := s.orchestrator.Pause(r.PathValue("name")); err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "paused"})
+}
+
+func (s *Server) resumeWorker(w http.ResponseWriter, r *http.Request) {
+	if err := s.orchestrator.Resume(r.PathValue("name")); err != nil {
+		writeError(w, statusFor(err), err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "resumed"})
+}
+
+func (s *Server) listRuns(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			writeError(w, http.StatusBadRequest, errors.New("limit must be a positive integer"))
+			return
+		}
+		limit = min(parsed, 500)
+	}
+
+	runs, err := s.runs.GetRecentRuns(r.URL.Query().Get("worker"), limit)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func statusFor(err error) int {
+	switch {
+	case errors.Is(err, core.ErrWorkerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, core.ErrWorkerBusy):
+		return http.StatusConflict
+	case errors.Is(err, core.ErrTargetUnsupported):
+		return http.StatusBadRequest
+	case errors.Is(err, core.ErrNotRunning):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
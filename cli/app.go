@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"io"
+	"os"
+	"personal-homepage-service/config"
+	"personal-homepage-service/core"
+	"personal-homepage-service/workers/deferred"
+	"personal-homepage-service/workers/digest"
+	"personal-homepage-service/workers/shipments"
+	"personal-homepage-service/workers/webhooks"
+)
+
+const usage = `Usage: personal-homepage-service <command> [arguments]
+
+Commands:
+  serve                          Start the scheduler and admin server (default)
+  run-once <worker>              Run a worker synchronously and exit
+  list-workers                   Print registered workers and their schedules
+  migrate [up|down [n]|version]  Apply, roll back or report schema migrations
+  shipments check <tracking#>    Run a carrier lookup without saving
+  shipments carriers             Report which carriers are configured
+  shipments unmapped             List carrier status codes with no mapping
+`
+
+type command func(env *environment, args []string) error
+
+var commands = map[string]command{
+	"serve":        serve,
+	"run-once":     runOnce,
+	"list-workers": listWorkers,
+	"migrate":      migrate,
+	"shipments":    shipmentsCommand,
+}
+
+// environment holds the dependencies shared by every command.
+type environment struct {
+	cfg    *config.Config
+	logger *zap.Logger
+	db     *gorm.DB
+	events *core.EventBus
+	stdout io.Writer
+}
+
+// Run executes the command named by args and returns the process exit code.
+func Run(args []string) int {
+	name := "serve"
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" || name == "-h" || name == "--help" {
+		fmt.Print(usage)
+		return 0
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", name, usage)
+		return 2
+	}
+
+	env, err := newEnvironment()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer func() {
+		_ = env.logger.Sync()
+	}()
+
+	if err := cmd(env, args); err != nil {
+		env.logger.Error(err.Error(), zap.String("command", name))
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	return 0
+}
+
+func newEnvironment() (*environment, error) {
+	cfg := config.LoadConfig()
+	logger, err := core.NewLogger(*cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := gorm.Open(postgres.Open(cfg.DSN), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	env := &environment{
+		cfg:    cfg,
+		logger: logger,
+		db:     db,
+		events: core.NewEventBus(logger),
+		stdout: os.Stdout,
+	}
+	subscribe(env)
+
+	return env, nil
+}
+
+func (env *environment) workers() []core.Worker {
+	return []core.Worker{
+		shipments.NewWorker(env.logger, *env.cfg, env.db, env.events),
+		webhooks.NewWorker(env.logger, *env.cfg, env.db),
+		digest.NewWorker(env.logger, *env.cfg, env.db, env.dispatcher()),
+		deferred.NewWorker(env.logger, env.dispatcher()),
+	}
+}
+
+func (env *environment) orchestrator() *core.Orchestrator {
+	return core.NewOrchestrator(env.logger, *env.cfg, env.db, env.workers())
+}
@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+func listWorkers(env *environment, args []string) error {
+	if len(args) != 0 {
+		return errors.New("list-workers takes no arguments")
+	}
+
+	w := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tSCHEDULE\tNEXT RUN")
+
+	for _, status := range env.orchestrator().Workers() {
+		next := "-"
+		if status.NextRun != nil {
+			next = status.NextRun.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", status.Name, status.Schedule, next)
+	}
+
+	return w.Flush()
+}
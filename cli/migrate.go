@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"personal-homepage-service/migrations"
+	"strconv"
+)
+
+const migrateUsage = "usage: migrate [up | down [steps] | version]"
+
+func migrate(env *environment, args []string) error {
+	migrator := migrations.NewMigrator(env.logger, env.db)
+
+	if len(args) == 0 {
+		args = []string{"up"}
+	}
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			return err
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, err := strconv.Atoi(args[1])
+			if err != nil || parsed <= 0 {
+				return errors.New(migrateUsage)
+			}
+			steps = parsed
+		}
+		if err := migrator.Down(steps); err != nil {
+			return err
+		}
+	case "version":
+	default:
+		return errors.New(migrateUsage)
+	}
+
+	version, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+
+	_, _ = fmt.Fprintf(env.stdout, "schema version %d\n", version)
+	return nil
+}
@@ -0,0 +1,25 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"syscall"
+)
+
+func runOnce(env *environment, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: run-once <worker>")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := env.orchestrator().RunOnce(ctx, args[0]); err != nil {
+		return fmt.Errorf("worker %s failed: %w", args[0], err)
+	}
+
+	_, _ = fmt.Fprintf(env.stdout, "worker %s completed\n", args[0])
+	return nil
+}
@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"personal-homepage-service/admin"
+	"personal-homepage-service/core/repositories"
+	"personal-homepage-service/migrations"
+	"syscall"
+	"time"
+)
+
+func serve(env *environment, args []string) error {
+	if len(args) != 0 {
+		return errors.New("serve takes no arguments")
+	}
+
+	if env.cfg.MigrateOnStart {
+		if err := migrations.NewMigrator(env.logger, env.db).Up(); err != nil {
+			return err
+		}
+	}
+
+	orchestrator := env.orchestrator()
+
+	if err := orchestrator.Start(context.Background()); err != nil {
+		return err
+	}
+	defer orchestrator.Stop()
+
+	adminServer := admin.NewServer(env.logger, *env.cfg, orchestrator, repositories.NewRunRepository(env.db))
+	adminServer.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := adminServer.Shutdown(ctx); err != nil {
+			env.logger.Error(err.Error())
+		}
+	}()
+
+	// Wait for termination signal to exit gracefully
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+
+	return nil
+}
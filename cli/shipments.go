@@ -0,0 +1,162 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"os/signal"
+	"personal-homepage-service/workers/shipments"
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"personal-homepage-service/workers/shipments/repositories"
+	"personal-homepage-service/workers/shipments/tracking"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+func shipmentsCommand(env *environment, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: shipments <check|carriers|unmapped> [arguments]")
+	}
+
+	switch args[0] {
+	case "check":
+		return checkShipment(env, args[1:])
+	case "carriers":
+		return listCarriers(env)
+	case "unmapped":
+		return listUnmappedStatuses(env)
+	default:
+		return fmt.Errorf("unknown shipments command %q", args[0])
+	}
+}
+
+// checkShipment runs the carrier processor for a tracking number and prints
+// the results without saving them. Known shipments are looked up so their
+// carrier and tracking URL are used unless overridden by flags; otherwise the
+// carrier is detected from the number.
+func checkShipment(env *environment, args []string) error {
+	flags := flag.NewFlagSet("shipments check", flag.ContinueOnError)
+	carrier := flags.String("carrier", "", "carrier key, e.g. ups or uds")
+	trackingURL := flags.String("url", "", "tracking page URL for scraped carriers")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("usage: shipments check [-carrier key] [-url tracking-url] <tracking#>")
+	}
+
+	trackingNumber := flags.Arg(0)
+	repo := repositories.NewRepository(env.db)
+
+	shipment, err := repo.GetShipmentByTrackingNumber(trackingNumber)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	shipment.TrackingNumber = trackingNumber
+
+	if *carrier != "" {
+		shipment.Carrier = &models.ShipmentCarrier{Key: *carrier}
+	}
+	if *trackingURL != "" {
+		shipment.TrackingURL = *trackingURL
+	}
+	if shipment.Carrier == nil {
+		match, ok := tracking.Suggest(trackingNumber)
+		if !ok {
+			return fmt.Errorf("could not detect the carrier for %s; pass -carrier to choose a processor", trackingNumber)
+		}
+		shipment.Carrier = &models.ShipmentCarrier{Key: match.Carrier}
+		shipment.TrackingNumber = match.Number
+		if shipment.TrackingURL == "" {
+			shipment.TrackingURL = match.TrackingURL
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	result, err := shipments.NewWorker(env.logger, *env.cfg, env.db, env.events).Check(ctx, shipment)
+	if err != nil {
+		return err
+	}
+
+	printResults(env.stdout, shipment.Carrier.Key, result)
+	return nil
+}
+
+// listCarriers prints every registered carrier and whether its configuration
+// is valid.
+func listCarriers(env *environment) error {
+	reports, err := shipments.NewWorker(env.logger, *env.cfg, env.db, env.events).Carriers()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CARRIER\tCONFIGURED\tDETAILS")
+
+	for _, report := range reports {
+		_, _ = fmt.Fprintf(w, "%s\t%t\t%s\n", report.Key, report.Configured, report.Error)
+	}
+
+	return w.Flush()
+}
+
+func printResults(w io.Writer, carrier string, result *processors.CarrierTrackingResults) {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	_, _ = fmt.Fprintf(w, "Tracking number:  %s\n", result.TrackingNumber)
+	_, _ = fmt.Fprintf(w, "Carrier:          %s\n", carrier)
+	_, _ = fmt.Fprintf(w, "Status:           %s\n", result.Status)
+	_, _ = fmt.Fprintf(w, "Last location:    %s\n", result.LastLocation)
+	_, _ = fmt.Fprintf(w, "Window start:     %s\n", formatTime(result.DeliveryWindowStart))
+	_, _ = fmt.Fprintf(w, "Window end:       %s\n", formatTime(result.DeliveryWindowEnd))
+	_, _ = fmt.Fprintf(w, "Checked at:       %s\n", formatTime(result.LastCheckedAt))
+	if result.HandoffTrackingNumber != "" {
+		_, _ = fmt.Fprintf(w, "Handed off as:    %s\n", result.HandoffTrackingNumber)
+	}
+
+	if len(result.Events) == 0 {
+		return
+	}
+
+	_, _ = fmt.Fprintln(w, "\nEvents:")
+	for _, e := range result.Events {
+		_, _ = fmt.Fprintf(w, "  %s  %-18s  %-24s  %s\n", e.OccurredAt.Format(time.RFC3339), e.Status, e.Location, e.Description)
+	}
+}
+
+// listUnmappedStatuses prints carrier status codes that resolved to unknown
+// because carrier_status_mappings has no status for them.
+func listUnmappedStatuses(env *environment) error {
+	rows, err := repositories.NewRepository(env.db).GetUnmappedStatuses()
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(env.stdout, 0, 4, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CARRIER\tCODE\tSEEN\tLAST SEEN\tDESCRIPTION")
+
+	for _, row := range rows {
+		carrier, lastSeen := "", "-"
+		if row.Carrier != nil {
+			carrier = row.Carrier.Key
+		}
+		if row.LastSeenAt != nil {
+			lastSeen = row.LastSeenAt.Format(time.RFC3339)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", carrier, row.Code, row.Occurrences, lastSeen, row.Description)
+	}
+
+	return w.Flush()
+}
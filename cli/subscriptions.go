@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"context"
+	"gorm.io/gorm"
+	"personal-homepage-service/core"
+	"personal-homepage-service/metrics"
+	"personal-homepage-service/notifications"
+	"personal-homepage-service/notifications/email"
+	"personal-homepage-service/workers/shipments"
+	"personal-homepage-service/workers/webhooks"
+)
+
+// subscribe attaches every in-process event handler to the bus.
+func subscribe(env *environment) {
+	env.events.Subscribe(shipments.EventStatusChanged, func(_ context.Context, event core.Event) {
+		e := event.(shipments.StatusChanged)
+		metrics.ObserveStatusTransition(e.PreviousStatus, e.Status)
+	})
+
+	// Webhooks are queued in the transaction that saves the shipment, so a
+	// status change is never committed without its delivery or vice versa.
+	if outbox := webhooks.NewOutbox(*env.cfg); outbox.Enabled() {
+		env.events.SubscribeTx(shipments.EventStatusChanged, func(_ context.Context, tx *gorm.DB, event core.Event) error {
+			return outbox.EnqueueStatusChanged(tx, event.(shipments.StatusChanged))
+		})
+	}
+
+	// Milestone notifications are queued the same way and sent by the
+	// deferred_notifications worker, keeping SMTP off the save path.
+	if dispatcher := env.dispatcher(); dispatcher.Enabled() {
+		milestones := notifications.NewMilestones(env.logger, dispatcher)
+		env.events.SubscribeTx(shipments.EventStatusChanged, func(_ context.Context, tx *gorm.DB, event core.Event) error {
+			return milestones.HandleStatusChanged(tx, event.(shipments.StatusChanged))
+		})
+	}
+}
+
+// dispatcher builds the notification dispatcher over every configured channel.
+func (env *environment) dispatcher() *notifications.Dispatcher {
+	var notifiers []notifications.Notifier
+	if email.Configured(env.cfg.SMTP) {
+		notifiers = append(notifiers, email.NewSender(env.cfg.SMTP))
+	}
+	return notifications.NewDispatcher(env.logger, *env.cfg, env.db, notifiers...)
+}
@@ -4,6 +4,9 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type UpsApiConfig struct {
@@ -12,10 +15,92 @@ type UpsApiConfig struct {
 	ClientSecret string
 }
 
+type FedExApiConfig struct {
+	BaseUri      string
+	ClientId     string
+	ClientSecret string
+}
+
+type UspsApiConfig struct {
+	BaseUri      string
+	ClientId     string
+	ClientSecret string
+}
+
+type DhlApiConfig struct {
+	BaseUri string
+	ApiKey  string
+}
+
+type AdminConfig struct {
+	Addr  string
+	Token string
+}
+
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+type ShipmentsConfig struct {
+	Concurrency      int
+	DefaultRateLimit RateLimit
+	RateLimits       map[string]RateLimit
+	MaxRateWait      time.Duration
+	RetryAttempts    int
+	RetryBaseDelay   time.Duration
+	RetryMaxDelay    time.Duration
+	MaxFailures      int
+	FailureBaseDelay time.Duration
+	FailureMaxDelay  time.Duration
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+type WebhooksConfig struct {
+	URLs        []string
+	Secret      string
+	MaxAttempts int
+}
+
+type SmtpConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// QuietHours is a daily window, as offsets from midnight, during which
+// notifications are held back. End may be before Start for windows that
+// cross midnight.
+type QuietHours struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+type NotificationsConfig struct {
+	Statuses       []string
+	DigestSchedule string
+	QuietHours     *QuietHours
+	Location       *time.Location
+}
+
 type Config struct {
-	DSN           string
-	LogsDirectory string
-	UPSApi        *UpsApiConfig
+	DSN            string
+	MigrateOnStart bool
+	LogsDirectory  string
+	DrainTimeout   time.Duration
+	Admin          *AdminConfig
+	Shipments      *ShipmentsConfig
+	Webhooks       *WebhooksConfig
+	Notifications  *NotificationsConfig
+	SMTP           *SmtpConfig
+	UPSApi         *UpsApiConfig
+	FedExApi       *FedExApiConfig
+	USPSApi        *UspsApiConfig
+	DHLApi         *DhlApiConfig
 }
 
 func LoadConfig() *Config {
@@ -24,12 +109,215 @@ func LoadConfig() *Config {
 		log.Println("No .env file found, relying on environment variables")
 	}
 	return &Config{
-		DSN:           os.Getenv("DATABASE_DSN"),
-		LogsDirectory: os.Getenv("LOGS_DIRECTORY"),
+		DSN:            os.Getenv("DATABASE_DSN"),
+		MigrateOnStart: getBool("DATABASE_MIGRATE_ON_START", true),
+		LogsDirectory:  os.Getenv("LOGS_DIRECTORY"),
+		DrainTimeout:   getDuration("WORKER_DRAIN_TIMEOUT", 30*time.Second),
+		Admin: &AdminConfig{
+			Addr:  getString("ADMIN_ADDR", "127.0.0.1:8080"),
+			Token: os.Getenv("ADMIN_TOKEN"),
+		},
+		Shipments: &ShipmentsConfig{
+			Concurrency:      getInt("SHIPMENTS_CONCURRENCY", 4),
+			DefaultRateLimit: RateLimit{PerSecond: 1, Burst: 2},
+			// UPS and FedEx allow bursts against their APIs. The USPS API's
+			// default quota is 60 requests an hour and DHL's is 250 a day.
+			// UDS is a scraped website, so keep to one page at a time.
+			RateLimits: getRateLimits("SHIPMENTS_RATE_LIMITS", map[string]RateLimit{
+				"ups":   {PerSecond: 5, Burst: 5},
+				"fedex": {PerSecond: 5, Burst: 5},
+				"usps":  {PerSecond: 1.0 / 60, Burst: 5},
+				"dhl":   {PerSecond: 250.0 / 86400, Burst: 5},
+				"uds":   {PerSecond: 0.5, Burst: 1},
+			}),
+			// Shipments whose carrier has no request to spare within this long
+			// are deferred rather than holding up the run.
+			MaxRateWait:      getDuration("SHIPMENTS_MAX_RATE_WAIT", 30*time.Second),
+			RetryAttempts:    getInt("SHIPMENTS_RETRY_ATTEMPTS", 3),
+			RetryBaseDelay:   getDuration("SHIPMENTS_RETRY_BASE_DELAY", time.Second),
+			RetryMaxDelay:    getDuration("SHIPMENTS_RETRY_MAX_DELAY", 30*time.Second),
+			MaxFailures:      getInt("SHIPMENTS_MAX_FAILURES", 5),
+			FailureBaseDelay: getDuration("SHIPMENTS_FAILURE_BASE_DELAY", 15*time.Minute),
+			FailureMaxDelay:  getDuration("SHIPMENTS_FAILURE_MAX_DELAY", 24*time.Hour),
+			BreakerThreshold: getInt("SHIPMENTS_BREAKER_THRESHOLD", 5),
+			BreakerCooldown:  getDuration("SHIPMENTS_BREAKER_COOLDOWN", 10*time.Minute),
+		},
+		Webhooks: &WebhooksConfig{
+			URLs:        getList("WEBHOOK_URLS"),
+			Secret:      os.Getenv("WEBHOOK_SECRET"),
+			MaxAttempts: getInt("WEBHOOK_MAX_ATTEMPTS", 10),
+		},
+		Notifications: &NotificationsConfig{
+			Statuses:       getListOr("NOTIFY_STATUSES", []string{"delivered", "exception", "delayed"}),
+			DigestSchedule: getString("DIGEST_SCHEDULE", "0 7 * * *"),
+			QuietHours:     getQuietHours("NOTIFY_QUIET_HOURS"),
+			Location:       getLocation("NOTIFY_TIMEZONE"),
+		},
+		SMTP: &SmtpConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     getInt("SMTP_PORT", 587),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("SMTP_FROM"),
+			To:       getList("SMTP_TO"),
+		},
 		UPSApi: &UpsApiConfig{
 			BaseUri:      os.Getenv("UPS_API_BASE_URI"),
 			ClientId:     os.Getenv("UPS_API_CLIENT_ID"),
 			ClientSecret: os.Getenv("UPS_API_CLIENT_SECRET"),
 		},
+		FedExApi: &FedExApiConfig{
+			BaseUri:      os.Getenv("FEDEX_API_BASE_URI"),
+			ClientId:     os.Getenv("FEDEX_API_CLIENT_ID"),
+			ClientSecret: os.Getenv("FEDEX_API_CLIENT_SECRET"),
+		},
+		USPSApi: &UspsApiConfig{
+			BaseUri:      os.Getenv("USPS_API_BASE_URI"),
+			ClientId:     os.Getenv("USPS_API_CLIENT_ID"),
+			ClientSecret: os.Getenv("USPS_API_CLIENT_SECRET"),
+		},
+		DHLApi: &DhlApiConfig{
+			BaseUri: os.Getenv("DHL_API_BASE_URI"),
+			ApiKey:  os.Getenv("DHL_API_KEY"),
+		},
+	}
+}
+
+func getString(key string, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
+	return fallback
+}
+
+// getList splits a comma separated value, dropping blank entries.
+func getList(key string) []string {
+	var ret []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+func getListOr(key string, fallback []string) []string {
+	if list := getList(key); len(list) > 0 {
+		return list
+	}
+	return fallback
+}
+
+func getInt(key string, fallback int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	i, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer %q for %s, using %d", value, key, fallback)
+		return fallback
+	}
+
+	return i
+}
+
+// getRateLimits parses entries such as "ups=5:10,uds=0.5:1" (requests per
+// second and burst) and layers them over the defaults.
+func getRateLimits(key string, defaults map[string]RateLimit) map[string]RateLimit {
+	limits := make(map[string]RateLimit, len(defaults))
+	for k, v := range defaults {
+		limits[k] = v
+	}
+
+	value := os.Getenv(key)
+	if value == "" {
+		return limits
+	}
+
+	for _, entry := range strings.Split(value, ",") {
+		name, spec, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		rawRate, rawBurst, hasBurst := strings.Cut(spec, ":")
+		if !ok || !hasBurst {
+			log.Printf("Invalid rate limit %q in %s, ignoring", entry, key)
+			continue
+		}
+
+		perSecond, rateErr := strconv.ParseFloat(rawRate, 64)
+		burst, burstErr := strconv.Atoi(rawBurst)
+		if rateErr != nil || burstErr != nil || perSecond <= 0 || burst <= 0 {
+			log.Printf("Invalid rate limit %q in %s, ignoring", entry, key)
+			continue
+		}
+
+		limits[name] = RateLimit{PerSecond: perSecond, Burst: burst}
+	}
+
+	return limits
+}
+
+func getBool(key string, fallback bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean %q for %s, using %t", value, key, fallback)
+		return fallback
+	}
+
+	return b
+}
+
+func getDuration(key string, fallback time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Invalid duration %q for %s, using %s", value, key, fallback)
+		return fallback
+	}
+
+	return d
+}
+
+// getQuietHours parses a window such as "22:00-07:00". It returns nil when the
+// value is unset or invalid, disabling quiet hours.
+func getQuietHours(key string) *QuietHours {
+	value := os.Getenv(key)
+	if value == "" {
+		return nil
+	}
+
+	rawStart, rawEnd, ok := strings.Cut(value, "-")
+	start, startErr := time.Parse("15:04", strings.TrimSpace(rawStart))
+	end, endErr := time.Parse("15:04", strings.TrimSpace(rawEnd))
+	if !ok || startErr != nil || endErr != nil {
+		log.Printf("Invalid quiet hours %q for %s, disabling quiet hours", value, key)
+		return nil
+	}
+
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &QuietHours{Start: start.Sub(midnight), End: end.Sub(midnight)}
+}
+
+func getLocation(key string) *time.Location {
+	value := os.Getenv(key)
+	if value == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		log.Printf("Invalid time zone %q for %s, using local time", value, key)
+		return time.Local
+	}
+
+	return loc
 }
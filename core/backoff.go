@@ -0,0 +1,27 @@
+package core
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff returns a delay for the given attempt that doubles from base up to
+// maxDelay, with the upper half randomized so retries from concurrent callers
+// spread out. Attempts count from 1; anything lower is treated as the first.
+func Backoff(attempt int, base time.Duration, maxDelay time.Duration) time.Duration {
+	attempt = max(attempt, 1)
+
+	ceiling := maxDelay
+	if attempt < 32 {
+		if d := base << (attempt - 1); d > 0 && d < maxDelay {
+			ceiling = d
+		}
+	}
+
+	if ceiling <= 0 {
+		return 0
+	}
+
+	half := ceiling / 2
+	return half + rand.N(ceiling-half+1)
+}
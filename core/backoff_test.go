@@ -0,0 +1,35 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	const (
+		base     = time.Second
+		maxDelay = time.Minute
+	)
+
+	tests := []struct {
+		attempt int
+		ceiling time.Duration
+	}{
+		{-1, base},
+		{0, base},
+		{1, base},
+		{2, 2 * base},
+		{4, 8 * base},
+		{7, maxDelay},
+		{64, maxDelay},
+	}
+
+	for _, tt := range tests {
+		for range 20 {
+			got := Backoff(tt.attempt, base, maxDelay)
+			if got < tt.ceiling/2 || got > tt.ceiling {
+				t.Fatalf("Backoff(%d) = %s, want between %s and %s", tt.attempt, got, tt.ceiling/2, tt.ceiling)
+			}
+		}
+	}
+}
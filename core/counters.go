@@ -0,0 +1,53 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+type countersKey struct{}
+
+// RunCounters collects named tallies a worker reports during a single run.
+// A nil *RunCounters is valid and discards everything.
+type RunCounters struct {
+	mu     sync.Mutex
+	values map[string]int
+}
+
+func NewRunCounters() *RunCounters {
+	return &RunCounters{values: make(map[string]int)}
+}
+
+func (c *RunCounters) Add(name string, delta int) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[name] += delta
+}
+
+func (c *RunCounters) Snapshot() map[string]int {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := make(map[string]int, len(c.values))
+	for k, v := range c.values {
+		ret[k] = v
+	}
+	return ret
+}
+
+func WithRunCounters(ctx context.Context, c *RunCounters) context.Context {
+	return context.WithValue(ctx, countersKey{}, c)
+}
+
+// CountersFromContext returns the counters attached to ctx, or nil if the
+// run is not being recorded.
+func CountersFromContext(ctx context.Context) *RunCounters {
+	c, _ := ctx.Value(countersKey{}).(*RunCounters)
+	return c
+}
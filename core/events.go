@@ -0,0 +1,120 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"sync"
+)
+
+// Event is anything published on the EventBus. Name identifies the event type
+// for subscribers.
+type Event interface {
+	EventName() string
+}
+
+type EventHandler func(ctx context.Context, event Event)
+
+// TxEventHandler runs inside the database transaction that produced the
+// event, so whatever it writes commits or rolls back together with it.
+// Returning an error rolls the transaction back.
+type TxEventHandler func(ctx context.Context, tx *gorm.DB, event Event) error
+
+// EventBus delivers events to in-process subscribers synchronously, in the
+// order they subscribed. A panicking handler is logged and skipped so it
+// cannot break the publisher or other handlers.
+type EventBus struct {
+	logger     *zap.Logger
+	mu         sync.RWMutex
+	handlers   map[string][]EventHandler
+	all        []EventHandler
+	txHandlers map[string][]TxEventHandler
+}
+
+func NewEventBus(logger *zap.Logger) *EventBus {
+	return &EventBus{
+		logger:     logger,
+		handlers:   make(map[string][]EventHandler),
+		txHandlers: make(map[string][]TxEventHandler),
+	}
+}
+
+func (b *EventBus) Subscribe(name string, handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[name] = append(b.handlers[name], handler)
+}
+
+// SubscribeAll registers a handler for every event regardless of name.
+func (b *EventBus) SubscribeAll(handler EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.all = append(b.all, handler)
+}
+
+// SubscribeTx registers a handler that runs inside the publisher's
+// transaction. Use it for writes, such as an outbox row, that must not exist
+// without the change that caused them.
+func (b *EventBus) SubscribeTx(name string, handler TxEventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.txHandlers[name] = append(b.txHandlers[name], handler)
+}
+
+// PublishTx delivers event to the transactional subscribers within tx. It
+// stops at the first handler that fails and returns its error so the caller
+// can roll back; a panic is treated as a failure.
+func (b *EventBus) PublishTx(ctx context.Context, tx *gorm.DB, event Event) error {
+	b.mu.RLock()
+	handlers := append([]TxEventHandler(nil), b.txHandlers[event.EventName()]...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := b.dispatchTx(ctx, tx, event, handler); err != nil {
+			return fmt.Errorf("%s handler failed: %w", event.EventName(), err)
+		}
+	}
+	return nil
+}
+
+func (b *EventBus) Publish(ctx context.Context, event Event) {
+	b.mu.RLock()
+	handlers := make([]EventHandler, 0, len(b.handlers[event.EventName()])+len(b.all))
+	handlers = append(handlers, b.handlers[event.EventName()]...)
+	handlers = append(handlers, b.all...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		b.dispatch(ctx, event, handler)
+	}
+}
+
+func (b *EventBus) dispatch(ctx context.Context, event Event, handler EventHandler) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Error("Event handler panicked",
+				zap.String("event", event.EventName()),
+				zap.Any("panic", r),
+				zap.Stack("stack"),
+			)
+		}
+	}()
+
+	handler(ctx, event)
+}
+
+func (b *EventBus) dispatchTx(ctx context.Context, tx *gorm.DB, event Event, handler TxEventHandler) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			b.logger.Error("Event handler panicked",
+				zap.String("event", event.EventName()),
+				zap.Any("panic", r),
+				zap.Stack("stack"),
+			)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return handler(ctx, tx, event)
+}
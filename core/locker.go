@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"database/sql/driver"
+	"gorm.io/gorm"
+)
+
+// Locker hands out Postgres session-level advisory locks so that only one
+// replica runs a given worker at a time.
+type Locker struct {
+	db *gorm.DB
+}
+
+func NewLocker(db *gorm.DB) *Locker {
+	return &Locker{db: db}
+}
+
+// TryLock attempts to take the advisory lock for key without blocking. When
+// acquired is true the caller must invoke unlock once its work is done.
+func (l *Locker) TryLock(ctx context.Context, key string) (unlock func() error, acquired bool, err error) {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Advisory locks belong to the session, so the lock and unlock must share
+	// a dedicated connection rather than going through the pool.
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", key).Scan(&acquired); err != nil {
+		_ = conn.Close()
+		return nil, false, err
+	}
+
+	if !acquired {
+		return nil, false, conn.Close()
+	}
+
+	unlock = func() error {
+		var released bool
+		err := conn.QueryRowContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", key).Scan(&released)
+		if err != nil || !released {
+			// Never hand a connection that may still hold the lock back to
+			// the pool; discarding it ends the session and frees the lock.
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+		_ = conn.Close()
+		return err
+	}
+
+	return unlock, true, nil
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+const (
+	RunOutcomeRunning   = "running"
+	RunOutcomeSucceeded = "succeeded"
+	RunOutcomeFailed    = "failed"
+	RunOutcomeCancelled = "cancelled"
+	RunOutcomeSkipped   = "skipped"
+)
+
+const (
+	RunTriggerSchedule = "schedule"
+	RunTriggerManual   = "manual"
+)
+
+// WorkerRun represents worker_runs table
+type WorkerRun struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Worker    string    `gorm:"size:100;not null;index"`
+	Instance  string    `gorm:"size:100"`
+	Trigger   string    `gorm:"size:20"`
+	StartedAt time.Time `gorm:"not null;index"`
+	EndedAt   *time.Time
+	Outcome   string         `gorm:"size:20;not null"`
+	Error     string         `gorm:"type:text"`
+	Counters  map[string]int `gorm:"type:jsonb;serializer:json"`
+}
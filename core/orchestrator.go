@@ -2,46 +2,440 @@ package core
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"os"
+	"personal-homepage-service/config"
+	"personal-homepage-service/core/models"
+	"personal-homepage-service/core/repositories"
+	"personal-homepage-service/metrics"
+	"sync"
 	"time"
 )
 
+var (
+	ErrWorkerNotFound    = errors.New("worker not found")
+	ErrWorkerBusy        = errors.New("worker is busy")
+	ErrTargetUnsupported = errors.New("worker does not support targeted runs")
+	ErrNotRunning        = errors.New("orchestrator is not running")
+	ErrLockHeld          = errors.New("worker lock held by another instance")
+)
+
+type WorkerStatus struct {
+	Name     string     `json:"name"`
+	Schedule string     `json:"schedule"`
+	NextRun  *time.Time `json:"next_run"`
+	Paused   bool       `json:"paused"`
+	Ready    bool       `json:"ready"`
+	// Invalid holds the validation error of a worker left unscheduled.
+	Invalid string `json:"invalid,omitempty"`
+}
+
 type Orchestrator struct {
-	logger  *zap.Logger
-	workers []Worker
+	logger       *zap.Logger
+	workers      []Worker
+	runs         *repositories.RunRepository
+	locker       *Locker
+	instance     string
+	drainTimeout time.Duration
+	cron         *cron.Cron
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+	mu           sync.Mutex
+	stopping     bool
+	paused       map[string]bool
+	invalid      map[string]string
 }
 
-func NewOrchestrator(logger *zap.Logger, workers []Worker) *Orchestrator {
-	return &Orchestrator{logger, workers}
+func NewOrchestrator(logger *zap.Logger, cfg config.Config, db *gorm.DB, workers []Worker) *Orchestrator {
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+
+	return &Orchestrator{
+		logger:       logger,
+		workers:      workers,
+		runs:         repositories.NewRunRepository(db),
+		locker:       NewLocker(db),
+		instance:     instance,
+		drainTimeout: cfg.DrainTimeout,
+		paused:       make(map[string]bool),
+		invalid:      make(map[string]string),
+	}
 }
 
-func (o *Orchestrator) Start(ctx context.Context) (*cron.Cron, error) {
-	c := cron.New()
+func (o *Orchestrator) Start(ctx context.Context) error {
+	o.ctx, o.cancel = context.WithCancel(ctx)
+	o.cron = cron.New()
 
 	for _, worker := range o.workers {
-		_, err := c.AddFunc(worker.Schedule(), func() {
-			now := time.Now()
-			if worker.Ready(now) {
-				go worker.Execute()
+		if validated, ok := worker.(ValidatedWorker); ok {
+			// One misconfigured worker must not keep the others from
+			// running, so it is left unscheduled instead.
+			if err := validated.Validate(); err != nil {
+				o.logger.Error("Invalid worker configuration, worker not scheduled",
+					zap.String("worker", worker.Name()),
+					zap.Error(err),
+				)
+				o.mu.Lock()
+				o.invalid[worker.Name()] = err.Error()
+				o.mu.Unlock()
+				continue
 			}
+		}
+
+		_, err := o.cron.AddFunc(worker.Schedule(), func() {
+			o.scheduled(worker)
 		})
 
 		if err != nil {
 			o.logger.Error("Error adding cron job",
-				zap.String("worker", fmt.Sprintf("%T", worker)),
+				zap.String("worker", worker.Name()),
 				zap.String("details", err.Error()),
 			)
-			return nil, err
+			o.cancel()
+			return err
 		}
 
 		o.logger.Info("Worker started",
-			zap.String("worker", fmt.Sprintf("%T", worker)),
+			zap.String("worker", worker.Name()),
 			zap.String("schedule", worker.Schedule()),
 		)
 	}
 
-	c.Start()
-	return c, nil
+	o.cron.Start()
+	return nil
+}
+
+// Stop halts scheduling, cancels in-flight runs and waits up to the drain
+// timeout for them to return.
+func (o *Orchestrator) Stop() {
+	if o.cron == nil {
+		return
+	}
+
+	o.mu.Lock()
+	o.stopping = true
+	o.mu.Unlock()
+
+	o.cron.Stop()
+	o.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		o.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		o.logger.Info("All workers drained")
+	case <-time.After(o.drainTimeout):
+		o.logger.Warn("Timed out waiting for workers to drain",
+			zap.Duration("timeout", o.drainTimeout),
+		)
+	}
+}
+
+// Workers reports the schedule and gating state of every registered worker.
+func (o *Orchestrator) Workers() []WorkerStatus {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := time.Now()
+	ret := make([]WorkerStatus, 0, len(o.workers))
+
+	for _, worker := range o.workers {
+		status := WorkerStatus{
+			Name:     worker.Name(),
+			Schedule: worker.Schedule(),
+			Paused:   o.paused[worker.Name()],
+			Ready:    worker.Ready(now),
+			Invalid:  o.invalid[worker.Name()],
+		}
+
+		if status.Invalid != "" {
+			ret = append(ret, status)
+			continue
+		}
+
+		if schedule, err := cron.ParseStandard(worker.Schedule()); err == nil {
+			next := schedule.Next(now)
+			status.NextRun = &next
+		}
+
+		ret = append(ret, status)
+	}
+
+	return ret
+}
+
+// Trigger starts a run of the named worker immediately, outside its schedule.
+func (o *Orchestrator) Trigger(name string) error {
+	worker, err := o.find(name)
+	if err != nil {
+		return err
+	}
+
+	return o.dispatch(worker, models.RunTriggerManual, worker.Execute)
+}
+
+// TriggerTarget starts a run of the named worker limited to a single item.
+func (o *Orchestrator) TriggerTarget(name string, target string) error {
+	worker, err := o.find(name)
+	if err != nil {
+		return err
+	}
+
+	targeted, ok := worker.(TargetedWorker)
+	if !ok {
+		return ErrTargetUnsupported
+	}
+
+	return o.dispatch(worker, models.RunTriggerManual, func(ctx context.Context) error {
+		return targeted.ExecuteTarget(ctx, target)
+	})
+}
+
+// RunOnce executes the named worker synchronously, outside the scheduler, and
+// returns the outcome of the run.
+func (o *Orchestrator) RunOnce(ctx context.Context, name string) error {
+	worker, err := o.find(name)
+	if err != nil {
+		return err
+	}
+
+	if !worker.Ready(time.Now()) {
+		return ErrWorkerBusy
+	}
+
+	o.wg.Add(1)
+	return o.run(ctx, worker, models.RunTriggerManual, worker.Execute)
+}
+
+// Pause stops scheduled runs of the named worker until it is resumed. Manual
+// triggers are still honored.
+func (o *Orchestrator) Pause(name string) error {
+	return o.setPaused(name, true)
+}
+
+func (o *Orchestrator) Resume(name string) error {
+	return o.setPaused(name, false)
+}
+
+func (o *Orchestrator) setPaused(name string, paused bool) error {
+	if _, err := o.find(name); err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	o.paused[name] = paused
+	o.mu.Unlock()
+
+	o.logger.Info("Worker pause state changed",
+		zap.String("worker", name),
+		zap.Bool("paused", paused),
+	)
+	return nil
+}
+
+func (o *Orchestrator) find(name string) (Worker, error) {
+	for _, worker := range o.workers {
+		if worker.Name() == name {
+			return worker, nil
+		}
+	}
+	return nil, ErrWorkerNotFound
+}
+
+func (o *Orchestrator) scheduled(worker Worker) {
+	o.mu.Lock()
+	paused := o.paused[worker.Name()]
+	o.mu.Unlock()
+
+	if paused {
+		o.logger.Info("Worker paused, scheduled run skipped",
+			zap.String("worker", worker.Name()),
+		)
+		return
+	}
+
+	_ = o.dispatch(worker, models.RunTriggerSchedule, worker.Execute)
+}
+
+func (o *Orchestrator) dispatch(worker Worker, trigger string, exec func(context.Context) error) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.ctx == nil || o.stopping {
+		return ErrNotRunning
+	}
+
+	if !worker.Ready(time.Now()) {
+		return ErrWorkerBusy
+	}
+
+	o.wg.Add(1)
+	go func() {
+		_ = o.run(o.ctx, worker, trigger, exec)
+	}()
+	return nil
+}
+
+func (o *Orchestrator) run(ctx context.Context, worker Worker, trigger string, exec func(context.Context) error) error {
+	defer o.wg.Done()
+
+	unlock, acquired, err := o.locker.TryLock(ctx, "worker:"+worker.Name())
+	if err != nil {
+		o.logger.Error("Failed to acquire worker lock",
+			zap.String("worker", worker.Name()),
+			zap.Error(err),
+		)
+		err = fmt.Errorf("failed to acquire worker lock: %w", err)
+		o.finishRun(o.startRun(worker, trigger), nil, err)
+		return err
+	}
+
+	if !acquired {
+		holder := o.lockHolder(worker)
+		o.logger.Info("Worker lock held by another instance, run skipped",
+			zap.String("worker", worker.Name()),
+			zap.String("instance", o.instance),
+			zap.String("holder", holder),
+		)
+		o.skipRun(worker, trigger, holder)
+		return ErrLockHeld
+	}
+
+	defer func() {
+		if err := unlock(); err != nil {
+			o.logger.Error("Failed to release worker lock",
+				zap.String("worker", worker.Name()),
+				zap.Error(err),
+			)
+		}
+	}()
+
+	o.logger.Info("Worker lock acquired",
+		zap.String("worker", worker.Name()),
+		zap.String("instance", o.instance),
+	)
+
+	run := o.startRun(worker, trigger)
+	counters := NewRunCounters()
+
+	err = o.execute(WithRunCounters(ctx, counters), worker, exec)
+	if err != nil {
+		o.logger.Error("Worker execution failed",
+			zap.String("worker", worker.Name()),
+			zap.Error(err),
+		)
+	}
+
+	o.finishRun(run, counters, err)
+	return err
+}
+
+// execute runs the worker, converting a panic into an error so a single bad
+// run never takes down the scheduler or the other workers.
+func (o *Orchestrator) execute(ctx context.Context, worker Worker, exec func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			o.logger.Error("Worker panicked",
+				zap.String("worker", worker.Name()),
+				zap.Any("panic", r),
+				zap.Stack("stack"),
+			)
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return exec(ctx)
+}
+
+func (o *Orchestrator) startRun(worker Worker, trigger string) *models.WorkerRun {
+	run := &models.WorkerRun{
+		Worker:    worker.Name(),
+		Instance:  o.instance,
+		Trigger:   trigger,
+		StartedAt: time.Now().UTC(),
+		Outcome:   models.RunOutcomeRunning,
+	}
+
+	if err := o.runs.CreateRun(run); err != nil {
+		o.logger.Error("Failed to record worker run start",
+			zap.String("worker", run.Worker),
+			zap.Error(err),
+		)
+	}
+
+	return run
+}
+
+func (o *Orchestrator) finishRun(run *models.WorkerRun, counters *RunCounters, err error) {
+	ended := time.Now().UTC()
+	run.EndedAt = &ended
+	run.Counters = counters.Snapshot()
+
+	switch {
+	case err == nil:
+		run.Outcome = models.RunOutcomeSucceeded
+	case errors.Is(err, context.Canceled):
+		run.Outcome = models.RunOutcomeCancelled
+		run.Error = err.Error()
+	default:
+		run.Outcome = models.RunOutcomeFailed
+		run.Error = err.Error()
+	}
+
+	o.recordRun(run)
+}
+
+// skipRun records a run that did not start because holder, another instance,
+// held the worker lock.
+func (o *Orchestrator) skipRun(worker Worker, trigger string, holder string) {
+	now := time.Now().UTC()
+	run := &models.WorkerRun{
+		Worker:    worker.Name(),
+		Instance:  o.instance,
+		Trigger:   trigger,
+		StartedAt: now,
+		EndedAt:   &now,
+		Outcome:   models.RunOutcomeSkipped,
+		Error:     fmt.Sprintf("%s: %s", ErrLockHeld, holder),
+	}
+
+	o.recordRun(run)
+}
+
+// lockHolder returns the instance of the worker's in-progress run, which is
+// the one holding its lock, or "unknown" if that run is not recorded.
+func (o *Orchestrator) lockHolder(worker Worker) string {
+	run, err := o.runs.GetRunningRun(worker.Name())
+	if err != nil {
+		o.logger.Error("Failed to look up worker lock holder",
+			zap.String("worker", worker.Name()),
+			zap.Error(err),
+		)
+	}
+	if run == nil || run.Instance == "" {
+		return "unknown"
+	}
+	return run.Instance
+}
+
+func (o *Orchestrator) recordRun(run *models.WorkerRun) {
+	metrics.ObserveWorkerRun(run.Worker, run.Outcome, run.EndedAt.Sub(run.StartedAt))
+
+	if err := o.runs.SaveRun(run); err != nil {
+		o.logger.Error("Failed to record worker run result",
+			zap.String("worker", run.Worker),
+			zap.Error(err),
+		)
+	}
 }
@@ -0,0 +1,82 @@
+package repositories
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"personal-homepage-service/core/models"
+)
+
+type RunRepository struct {
+	db *gorm.DB
+}
+
+func NewRunRepository(db *gorm.DB) *RunRepository {
+	return &RunRepository{db: db}
+}
+
+func (r *RunRepository) CreateRun(run *models.WorkerRun) error {
+	return r.db.Create(run).Error
+}
+
+func (r *RunRepository) SaveRun(run *models.WorkerRun) error {
+	return r.db.Save(run).Error
+}
+
+// GetRecentRuns returns the latest runs, newest first. An empty worker name
+// returns runs across all workers.
+func (r *RunRepository) GetRecentRuns(worker string, limit int) ([]models.WorkerRun, error) {
+	var runs []models.WorkerRun
+	query := r.db.Order("started_at desc").Limit(limit)
+	if worker != "" {
+		query = query.Where("worker = ?", worker)
+	}
+	err := query.Find(&runs).Error
+	return runs, err
+}
+
+// GetLastRun returns the most recent run for a worker, or nil if it never ran.
+func (r *RunRepository) GetLastRun(worker string) (*models.WorkerRun, error) {
+	return r.first(r.db.Where("worker = ?", worker))
+}
+
+// GetLastSuccessfulRun returns the most recent successful run for a worker, or
+// nil if it never succeeded.
+func (r *RunRepository) GetLastSuccessfulRun(worker string) (*models.WorkerRun, error) {
+	return r.first(r.db.Where("worker = ? AND outcome = ?", worker, models.RunOutcomeSucceeded))
+}
+
+// GetRunningRun returns the most recent run of a worker that has not finished,
+// or nil if none is in progress.
+func (r *RunRepository) GetRunningRun(worker string) (*models.WorkerRun, error) {
+	return r.first(r.db.Where("worker = ? AND outcome = ?", worker, models.RunOutcomeRunning))
+}
+
+// CountConsecutiveFailures counts failed runs since the worker last succeeded.
+func (r *RunRepository) CountConsecutiveFailures(worker string) (int64, error) {
+	query := r.db.Model(&models.WorkerRun{}).
+		Where("worker = ? AND outcome = ?", worker, models.RunOutcomeFailed)
+
+	last, err := r.GetLastSuccessfulRun(worker)
+	if err != nil {
+		return 0, err
+	}
+	if last != nil {
+		query = query.Where("started_at > ?", last.StartedAt)
+	}
+
+	var count int64
+	err = query.Count(&count).Error
+	return count, err
+}
+
+func (r *RunRepository) first(query *gorm.DB) (*models.WorkerRun, error) {
+	var run models.WorkerRun
+	err := query.Order("started_at desc").First(&run).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
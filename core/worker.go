@@ -1,9 +1,29 @@
 package core
 
-import "time"
+import (
+	"context"
+	"time"
+)
 
 type Worker interface {
+	Name() string
 	Schedule() string
 	Ready(now time.Time) bool
-	Execute()
+	Execute(ctx context.Context) error
+}
+
+// TargetedWorker is implemented by workers that can run against a single
+// item, such as one shipment, instead of their whole workload.
+type TargetedWorker interface {
+	Worker
+	ExecuteTarget(ctx context.Context, target string) error
+}
+
+// ValidatedWorker is implemented by workers whose configuration can be wrong
+// in ways that would otherwise only show up run after run. The orchestrator
+// does not schedule a worker that fails validation; it can still be triggered
+// manually.
+type ValidatedWorker interface {
+	Worker
+	Validate() error
 }
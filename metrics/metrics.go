@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"time"
+)
+
+const namespace = "homepage"
+
+var (
+	workerRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "worker_runs_total",
+		Help:      "Worker runs by outcome.",
+	}, []string{"worker", "outcome"})
+
+	workerRunDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "worker_run_duration_seconds",
+		Help:      "Duration of worker runs.",
+		Buckets:   []float64{0.1, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"worker", "outcome"})
+
+	carrierRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "carrier_requests_total",
+		Help:      "Outbound carrier requests by HTTP status code, or \"error\" for transport failures.",
+	}, []string{"carrier", "operation", "code"})
+
+	carrierRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "carrier_request_duration_seconds",
+		Help:      "Latency of outbound carrier requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"carrier", "operation"})
+
+	carrierTokenRefreshes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "carrier_oauth_token_refreshes_total",
+		Help:      "OAuth access tokens requested from carriers.",
+	}, []string{"carrier", "outcome"})
+
+	carrierCircuitState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "carrier_circuit_state",
+		Help:      "Set to 1 for the current circuit breaker state of each carrier.",
+	}, []string{"carrier", "state"})
+
+	shipmentTransitions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "shipment_status_transitions_total",
+		Help:      "Shipment status changes by previous and new status.",
+	}, []string{"from", "to"})
+
+	openShipments = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "open_shipments",
+		Help:      "Open shipments by status as of the last shipments run.",
+	}, []string{"status"})
+)
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+func ObserveWorkerRun(worker string, outcome string, duration time.Duration) {
+	workerRuns.WithLabelValues(worker, outcome).Inc()
+	workerRunDuration.WithLabelValues(worker, outcome).Observe(duration.Seconds())
+}
+
+func ObserveTokenRefresh(carrier string, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	carrierTokenRefreshes.WithLabelValues(carrier, outcome).Inc()
+}
+
+var circuitStates = []string{"closed", "open", "half_open"}
+
+// SetCircuitState marks state as the current breaker state for carrier and
+// clears the others.
+func SetCircuitState(carrier string, state string) {
+	for _, s := range circuitStates {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		carrierCircuitState.WithLabelValues(carrier, s).Set(value)
+	}
+}
+
+func ObserveStatusTransition(from string, to string) {
+	shipmentTransitions.WithLabelValues(from, to).Inc()
+}
+
+// SetOpenShipments replaces the open shipment gauges with the given counts,
+// so statuses that no longer have shipments drop back to zero.
+func SetOpenShipments(countsByStatus map[string]int) {
+	openShipments.Reset()
+	for status, count := range countsByStatus {
+		openShipments.WithLabelValues(status).Set(float64(count))
+	}
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type operationKey struct{}
+
+// WithOperation labels carrier requests made with ctx, e.g. "oauth" or
+// "track", so their metrics can be told apart.
+func WithOperation(ctx context.Context, operation string) context.Context {
+	return context.WithValue(ctx, operationKey{}, operation)
+}
+
+func operationFrom(ctx context.Context) string {
+	if operation, ok := ctx.Value(operationKey{}).(string); ok {
+		return operation
+	}
+	return "request"
+}
+
+type carrierTransport struct {
+	carrier string
+	next    http.RoundTripper
+}
+
+// CarrierTransport wraps next, recording latency and status codes for every
+// request made to a carrier. A nil next uses http.DefaultTransport.
+func CarrierTransport(carrier string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &carrierTransport{carrier, next}
+}
+
+func (t *carrierTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	operation := operationFrom(req.Context())
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	carrierRequestDuration.WithLabelValues(t.carrier, operation).Observe(time.Since(start).Seconds())
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	carrierRequests.WithLabelValues(t.carrier, operation, code).Inc()
+
+	return resp, err
+}
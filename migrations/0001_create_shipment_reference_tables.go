@@ -0,0 +1,24 @@
+package migrations
+
+var createShipmentReferenceTables = Migration{
+	Version: 1,
+	Name:    "create_shipment_reference_tables",
+	Up: exec(
+		`CREATE TABLE IF NOT EXISTS shipment_statuses (
+			id       bigserial PRIMARY KEY,
+			key      varchar(50) NOT NULL UNIQUE,
+			label    varchar(50) NOT NULL UNIQUE,
+			is_final boolean NOT NULL
+		)`,
+		`CREATE TABLE IF NOT EXISTS shipment_carriers (
+			id    bigserial PRIMARY KEY,
+			key   varchar(50) NOT NULL UNIQUE,
+			label varchar(50) NOT NULL UNIQUE,
+			icon  varchar(256)
+		)`,
+	),
+	Down: exec(
+		`DROP TABLE IF EXISTS shipment_carriers`,
+		`DROP TABLE IF EXISTS shipment_statuses`,
+	),
+}
@@ -0,0 +1,24 @@
+package migrations
+
+var createShipments = Migration{
+	Version: 2,
+	Name:    "create_shipments",
+	Up: exec(
+		`CREATE TABLE IF NOT EXISTS shipments (
+			id                    bigserial PRIMARY KEY,
+			label                 text NOT NULL,
+			tracking_number       varchar(100) NOT NULL UNIQUE,
+			tracking_url          varchar(256),
+			delivery_window_start timestamptz,
+			delivery_window_end   timestamptz,
+			last_location         varchar(100),
+			last_checked_at       timestamptz,
+			thumbnail_url         varchar(256),
+			status_id             bigint REFERENCES shipment_statuses (id),
+			carrier_id            bigint REFERENCES shipment_carriers (id)
+		)`,
+	),
+	Down: exec(
+		`DROP TABLE IF EXISTS shipments`,
+	),
+}
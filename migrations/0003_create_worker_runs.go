@@ -0,0 +1,24 @@
+package migrations
+
+var createWorkerRuns = Migration{
+	Version: 3,
+	Name:    "create_worker_runs",
+	Up: exec(
+		`CREATE TABLE IF NOT EXISTS worker_runs (
+			id         bigserial PRIMARY KEY,
+			worker     varchar(100) NOT NULL,
+			instance   varchar(100),
+			trigger    varchar(20),
+			started_at timestamptz NOT NULL,
+			ended_at   timestamptz,
+			outcome    varchar(20) NOT NULL,
+			error      text,
+			counters   jsonb
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_worker_runs_worker ON worker_runs (worker)`,
+		`CREATE INDEX IF NOT EXISTS idx_worker_runs_started_at ON worker_runs (started_at)`,
+	),
+	Down: exec(
+		`DROP TABLE IF EXISTS worker_runs`,
+	),
+}
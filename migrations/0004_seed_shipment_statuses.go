@@ -0,0 +1,32 @@
+package migrations
+
+// Every key a processor can emit must exist here, otherwise
+// Repository.GetStatus fails and the shipment is never updated.
+var seedShipmentStatuses = Migration{
+	Version: 4,
+	Name:    "seed_shipment_statuses",
+	Up: exec(
+		`INSERT INTO shipment_statuses (key, label, is_final) VALUES
+			('unchecked',          'Unchecked',          false),
+			('pending',            'Pending',            false),
+			('accepted',           'Accepted',           false),
+			('in_transit',         'In Transit',         false),
+			('out_for_delivery',   'Out for Delivery',   false),
+			('attempted_delivery', 'Delivery Attempted', false),
+			('delayed',            'Delayed',            false),
+			('exception',          'Exception',          false),
+			('delivered',          'Delivered',          true),
+			('returned',           'Returned',           true),
+			('cancelled',          'Cancelled',          true),
+			('unknown',            'Unknown',            false),
+			('unsupported',        'Unsupported',        false)
+		ON CONFLICT DO NOTHING`,
+	),
+	Down: exec(
+		`DELETE FROM shipment_statuses WHERE key IN (
+			'unchecked', 'pending', 'accepted', 'in_transit', 'out_for_delivery',
+			'attempted_delivery', 'delayed', 'exception', 'delivered', 'returned',
+			'cancelled', 'unknown', 'unsupported'
+		) AND NOT EXISTS (SELECT 1 FROM shipments WHERE shipments.status_id = shipment_statuses.id)`,
+	),
+}
@@ -0,0 +1,16 @@
+package migrations
+
+var seedShipmentCarriers = Migration{
+	Version: 5,
+	Name:    "seed_shipment_carriers",
+	Up: exec(
+		`INSERT INTO shipment_carriers (key, label) VALUES
+			('ups', 'UPS'),
+			('uds', 'UDS')
+		ON CONFLICT DO NOTHING`,
+	),
+	Down: exec(
+		`DELETE FROM shipment_carriers WHERE key IN ('ups', 'uds')
+		AND NOT EXISTS (SELECT 1 FROM shipments WHERE shipments.carrier_id = shipment_carriers.id)`,
+	),
+}
@@ -0,0 +1,21 @@
+package migrations
+
+var addShipmentErrors = Migration{
+	Version: 6,
+	Name:    "add_shipment_errors",
+	Up: exec(
+		`ALTER TABLE shipments
+			ADD COLUMN IF NOT EXISTS last_error text,
+			ADD COLUMN IF NOT EXISTS last_error_at timestamptz`,
+		`INSERT INTO shipment_statuses (key, label, is_final) VALUES
+			('invalid', 'Invalid Tracking Number', true)
+		ON CONFLICT DO NOTHING`,
+	),
+	Down: exec(
+		`DELETE FROM shipment_statuses WHERE key = 'invalid'
+		AND NOT EXISTS (SELECT 1 FROM shipments WHERE shipments.status_id = shipment_statuses.id)`,
+		`ALTER TABLE shipments
+			DROP COLUMN IF EXISTS last_error_at,
+			DROP COLUMN IF EXISTS last_error`,
+	),
+}
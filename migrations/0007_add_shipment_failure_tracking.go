@@ -0,0 +1,23 @@
+package migrations
+
+var addShipmentFailureTracking = Migration{
+	Version: 7,
+	Name:    "add_shipment_failure_tracking",
+	Up: exec(
+		`ALTER TABLE shipments
+			ADD COLUMN IF NOT EXISTS consecutive_failures integer NOT NULL DEFAULT 0,
+			ADD COLUMN IF NOT EXISTS next_check_at timestamptz`,
+		// Not final: shipments in error keep being polled at the maximum
+		// backoff so they recover on their own once the carrier does.
+		`INSERT INTO shipment_statuses (key, label, is_final) VALUES
+			('error', 'Error', false)
+		ON CONFLICT DO NOTHING`,
+	),
+	Down: exec(
+		`DELETE FROM shipment_statuses WHERE key = 'error'
+		AND NOT EXISTS (SELECT 1 FROM shipments WHERE shipments.status_id = shipment_statuses.id)`,
+		`ALTER TABLE shipments
+			DROP COLUMN IF EXISTS next_check_at,
+			DROP COLUMN IF EXISTS consecutive_failures`,
+	),
+}
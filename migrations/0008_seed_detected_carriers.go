@@ -0,0 +1,21 @@
+package migrations
+
+// Carriers the tracking package can detect, so auto-assigned shipments always
+// have a row to point at even before a processor exists for them.
+var seedDetectedCarriers = Migration{
+	Version: 8,
+	Name:    "seed_detected_carriers",
+	Up: exec(
+		`INSERT INTO shipment_carriers (key, label) VALUES
+			('usps',   'USPS'),
+			('fedex',  'FedEx'),
+			('dhl',    'DHL'),
+			('amazon', 'Amazon'),
+			('ontrac', 'OnTrac')
+		ON CONFLICT DO NOTHING`,
+	),
+	Down: exec(
+		`DELETE FROM shipment_carriers WHERE key IN ('usps', 'fedex', 'dhl', 'amazon', 'ontrac')
+		AND NOT EXISTS (SELECT 1 FROM shipments WHERE shipments.carrier_id = shipment_carriers.id)`,
+	),
+}
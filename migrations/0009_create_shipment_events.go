@@ -0,0 +1,24 @@
+package migrations
+
+var createShipmentEvents = Migration{
+	Version: 9,
+	Name:    "create_shipment_events",
+	Up: exec(
+		`CREATE TABLE IF NOT EXISTS shipment_events (
+			id                  bigserial PRIMARY KEY,
+			shipment_id         bigint NOT NULL REFERENCES shipments (id) ON DELETE CASCADE,
+			occurred_at         timestamptz NOT NULL,
+			carrier_status_code varchar(50) NOT NULL DEFAULT '',
+			location            varchar(100),
+			description         varchar(256),
+			status              varchar(50)
+		)`,
+		// Carriers resend the whole timeline on every check, so this is what
+		// makes saving events idempotent.
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_shipment_events_identity
+			ON shipment_events (shipment_id, occurred_at, carrier_status_code)`,
+	),
+	Down: exec(
+		`DROP TABLE IF EXISTS shipment_events`,
+	),
+}
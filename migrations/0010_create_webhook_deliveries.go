@@ -0,0 +1,25 @@
+package migrations
+
+var createWebhookDeliveries = Migration{
+	Version: 10,
+	Name:    "create_webhook_deliveries",
+	Up: exec(
+		`CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id              bigserial PRIMARY KEY,
+			url             varchar(512) NOT NULL,
+			event           varchar(100) NOT NULL,
+			payload         jsonb NOT NULL,
+			status          varchar(20) NOT NULL,
+			attempts        integer NOT NULL DEFAULT 0,
+			next_attempt_at timestamptz NOT NULL,
+			last_error      text,
+			created_at      timestamptz NOT NULL,
+			delivered_at    timestamptz
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+			ON webhook_deliveries (next_attempt_at) WHERE status = 'pending'`,
+	),
+	Down: exec(
+		`DROP TABLE IF EXISTS webhook_deliveries`,
+	),
+}
@@ -0,0 +1,20 @@
+package migrations
+
+var createDigests = Migration{
+	Version: 11,
+	Name:    "create_digests",
+	Up: exec(
+		`CREATE TABLE IF NOT EXISTS digests (
+			id          bigserial PRIMARY KEY,
+			sent_at     timestamptz NOT NULL,
+			fingerprint varchar(64) NOT NULL,
+			arriving    integer NOT NULL DEFAULT 0,
+			late        integer NOT NULL DEFAULT 0,
+			delivered   integer NOT NULL DEFAULT 0
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_digests_sent_at ON digests (sent_at)`,
+	),
+	Down: exec(
+		`DROP TABLE IF EXISTS digests`,
+	),
+}
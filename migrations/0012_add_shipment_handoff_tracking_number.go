@@ -0,0 +1,14 @@
+package migrations
+
+var addShipmentHandoffTrackingNumber = Migration{
+	Version: 12,
+	Name:    "add_shipment_handoff_tracking_number",
+	Up: exec(
+		`ALTER TABLE shipments
+			ADD COLUMN IF NOT EXISTS handoff_tracking_number varchar(100)`,
+	),
+	Down: exec(
+		`ALTER TABLE shipments
+			DROP COLUMN IF EXISTS handoff_tracking_number`,
+	),
+}
@@ -0,0 +1,33 @@
+package migrations
+
+// Carriers without an API are tracked by scraping their tracking page as
+// described by a row here. LaserShip and Veho are seeded as carriers so
+// shipments can be assigned to them; their definitions, like OnTrac's, are
+// added as data once the page has been inspected.
+var createCarrierScrapeDefinitions = Migration{
+	Version: 13,
+	Name:    "create_carrier_scrape_definitions",
+	Up: exec(
+		`CREATE TABLE IF NOT EXISTS carrier_scrape_definitions (
+			id                bigserial PRIMARY KEY,
+			carrier_id        bigint NOT NULL UNIQUE REFERENCES shipment_carriers (id) ON DELETE CASCADE,
+			url_template      varchar(512) NOT NULL,
+			status_selector   varchar(256) NOT NULL,
+			location_selector varchar(256),
+			location_pattern  varchar(256),
+			eta_selector      varchar(256),
+			eta_pattern       varchar(256),
+			date_layouts      jsonb NOT NULL DEFAULT '[]',
+			status_map        jsonb NOT NULL DEFAULT '{}'
+		)`,
+		`INSERT INTO shipment_carriers (key, label) VALUES
+			('lasership', 'LaserShip'),
+			('veho',      'Veho')
+		ON CONFLICT DO NOTHING`,
+	),
+	Down: exec(
+		`DROP TABLE IF EXISTS carrier_scrape_definitions`,
+		`DELETE FROM shipment_carriers WHERE key IN ('lasership', 'veho')
+		AND NOT EXISTS (SELECT 1 FROM shipments WHERE shipments.carrier_id = shipment_carriers.id)`,
+	),
+}
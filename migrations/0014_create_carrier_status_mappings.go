@@ -0,0 +1,177 @@
+package migrations
+
+// Status mappings were compiled into each processor until carriers started
+// sending codes we had no release for. The seed is those maps, except that
+// packages held for pickup get their own status rather than delivered.
+var createCarrierStatusMappings = Migration{
+	Version: 14,
+	Name:    "create_carrier_status_mappings",
+	Up: exec(
+		`INSERT INTO shipment_statuses (key, label, is_final) VALUES
+			('awaiting_pickup', 'Awaiting Pickup', false)
+		ON CONFLICT DO NOTHING`,
+		// A NULL status_key marks a code a carrier sent that nobody has mapped
+		// yet; the processors record these as they see them.
+		`CREATE TABLE IF NOT EXISTS carrier_status_mappings (
+			id            bigserial PRIMARY KEY,
+			carrier_id    bigint NOT NULL REFERENCES shipment_carriers (id) ON DELETE CASCADE,
+			code          varchar(100) NOT NULL,
+			status_key    varchar(50) REFERENCES shipment_statuses (key),
+			description   varchar(256),
+			first_seen_at timestamptz,
+			last_seen_at  timestamptz,
+			occurrences   integer NOT NULL DEFAULT 0,
+			UNIQUE (carrier_id, code)
+		)`,
+		`INSERT INTO carrier_status_mappings (carrier_id, code, status_key, description)
+		SELECT c.id, v.code, v.status_key, v.description
+		FROM (VALUES
+			('ups', '003', 'pending', 'Shipment Ready for UPS'),
+			('ups', '005', 'accepted', 'In Transit'),
+			('ups', '006', 'out_for_delivery', 'Out for Delivery Today'),
+			('ups', '007', 'cancelled', 'Shipment Canceled'),
+			('ups', '011', 'delivered', 'Delivered'),
+			('ups', '012', 'in_transit', 'Clearance in Progress'),
+			('ups', '013', 'in_transit', 'Update'),
+			('ups', '014', 'in_transit', 'Cleared Customs'),
+			('ups', '016', 'exception', 'Held in Warehouse'),
+			('ups', '017', 'awaiting_pickup', 'Held for Customer Pickup'),
+			('ups', '018', 'exception', 'Hold for Pickup Requested'),
+			('ups', '019', 'delayed', 'Delivery Rescheduled'),
+			('ups', '021', 'out_for_delivery', 'Out for Delivery Today'),
+			('ups', '022', 'attempted_delivery', 'Delivery Attempted'),
+			('ups', '023', 'attempted_delivery', 'Delivery Attempted'),
+			('ups', '024', 'attempted_delivery', 'IsFinal Delivery Attempt Made'),
+			('ups', '025', 'in_transit', 'In Transit'),
+			('ups', '026', 'delivered', 'Delivered by Local Post Office'),
+			('ups', '027', 'in_transit', 'Address Change Requested'),
+			('ups', '028', 'in_transit', 'Delivery Address Changed'),
+			('ups', '029', 'exception', 'Address Information Required'),
+			('ups', '030', 'delayed', 'Local Post Office Delay'),
+			('ups', '032', 'delayed', 'Weather May Cause Delay'),
+			('ups', '033', 'returned', 'Return Requested'),
+			('ups', '035', 'returned', 'Returning to Sender'),
+			('ups', '038', 'accepted', 'Picked Up'),
+			('ups', '040', 'delivered', 'Delivered to UPS Access Point™'),
+			('ups', '042', 'in_transit', 'Service Upgraded'),
+			('ups', '044', 'in_transit', 'On Its Way to UPS'),
+			('ups', '045', 'in_transit', 'Order Processed: On its Way to UPS'),
+			('ups', '046', 'delayed', 'Delay'),
+			('ups', '047', 'in_transit', 'In Transit'),
+			('ups', '048', 'delayed', 'Delay'),
+			('ups', '049', 'exception', 'Delay: Attention Needed'),
+			('ups', '050', 'exception', 'Address Information Required'),
+			('ups', '051', 'delayed', 'Delay: Emergency Situation or Severe Weather'),
+			('ups', '052', 'delayed', 'Severe Weather Delay'),
+			('ups', '053', 'delayed', 'Severe Weather Delay'),
+			('ups', '054', 'delayed', 'Delivery Change Requested'),
+			('ups', '055', 'delayed', 'Rescheduled Delivery'),
+			('ups', '057', 'in_transit', 'On Its Way to a Local UPS Access Point™'),
+			('ups', '058', 'exception', 'Clearance Information Required'),
+			('ups', '065', 'attempted_delivery', 'Pickup Attempted'),
+			('ups', '070', 'in_transit', 'On Its Way to a Local UPS Access Point™'),
+			('ups', '071', 'out_for_delivery', 'Preparing for Delivery Today'),
+			('ups', '072', 'out_for_delivery', 'Loaded on Delivery Vehicle'),
+			('ups', '077', 'delivered', 'Scheduled for Pickup Today'),
+			('uds', 'Shipment Notification', 'pending', NULL),
+			('uds', 'Received', 'in_transit', NULL),
+			('uds', 'Out for Delivery', 'out_for_delivery', NULL),
+			('uds', 'Delivered', 'delivered', NULL),
+			('fedex', 'OC', 'pending', 'Shipment information sent to FedEx'),
+			('fedex', 'IN', 'pending', 'Initiated'),
+			('fedex', 'PU', 'accepted', 'Picked up'),
+			('fedex', 'PX', 'accepted', 'Picked up (see details)'),
+			('fedex', 'AA', 'in_transit', NULL),
+			('fedex', 'AC', 'in_transit', NULL),
+			('fedex', 'AF', 'in_transit', NULL),
+			('fedex', 'AP', 'in_transit', NULL),
+			('fedex', 'AR', 'in_transit', NULL),
+			('fedex', 'AX', 'in_transit', NULL),
+			('fedex', 'CC', 'in_transit', 'Cleared customs'),
+			('fedex', 'CH', 'in_transit', 'Location changed'),
+			('fedex', 'CP', 'in_transit', 'Clearance in progress'),
+			('fedex', 'DP', 'in_transit', NULL),
+			('fedex', 'DR', 'in_transit', NULL),
+			('fedex', 'DS', 'in_transit', NULL),
+			('fedex', 'EA', 'in_transit', NULL),
+			('fedex', 'ED', 'in_transit', NULL),
+			('fedex', 'EO', 'in_transit', NULL),
+			('fedex', 'EP', 'in_transit', NULL),
+			('fedex', 'FD', 'in_transit', NULL),
+			('fedex', 'HL', 'awaiting_pickup', 'Held at location for pickup'),
+			('fedex', 'IT', 'in_transit', NULL),
+			('fedex', 'IX', 'in_transit', NULL),
+			('fedex', 'LO', 'in_transit', NULL),
+			('fedex', 'OF', 'in_transit', NULL),
+			('fedex', 'OX', 'in_transit', 'Shipment information sent to USPS'),
+			('fedex', 'PF', 'in_transit', NULL),
+			('fedex', 'PL', 'in_transit', NULL),
+			('fedex', 'PM', 'in_transit', NULL),
+			('fedex', 'SF', 'in_transit', NULL),
+			('fedex', 'SP', 'in_transit', NULL),
+			('fedex', 'TR', 'in_transit', NULL),
+			('fedex', 'OD', 'out_for_delivery', NULL),
+			('fedex', 'AD', 'out_for_delivery', NULL),
+			('fedex', 'DD', 'delayed', NULL),
+			('fedex', 'DY', 'delayed', NULL),
+			('fedex', 'PD', 'delayed', NULL),
+			('fedex', 'CD', 'delayed', 'Clearance delay'),
+			('fedex', 'DE', 'exception', NULL),
+			('fedex', 'SE', 'exception', NULL),
+			('fedex', 'DL', 'delivered', NULL),
+			('fedex', 'RS', 'returned', NULL),
+			('fedex', 'CA', 'cancelled', NULL),
+			('usps', 'GX', 'pending', 'Shipping label created'),
+			('usps', 'MA', 'pending', 'Pre-shipment info sent to USPS'),
+			('usps', '03', 'accepted', 'Accepted at USPS origin facility'),
+			('usps', 'OA', 'accepted', NULL),
+			('usps', '80', 'accepted', 'Picked up by shipping partner'),
+			('usps', '81', 'accepted', NULL),
+			('usps', '07', 'in_transit', NULL),
+			('usps', '10', 'in_transit', NULL),
+			('usps', '14', 'in_transit', NULL),
+			('usps', '15', 'in_transit', 'Mis-sent'),
+			('usps', '16', 'awaiting_pickup', 'Available for pickup'),
+			('usps', '59', 'in_transit', 'Held at post office at customer request'),
+			('usps', 'A1', 'in_transit', NULL),
+			('usps', 'L1', 'in_transit', NULL),
+			('usps', 'PC', 'in_transit', NULL),
+			('usps', 'SF', 'in_transit', NULL),
+			('usps', 'T1', 'in_transit', NULL),
+			('usps', 'U1', 'in_transit', NULL),
+			('usps', 'OF', 'out_for_delivery', NULL),
+			('usps', '02', 'attempted_delivery', 'Notice left'),
+			('usps', '51', 'attempted_delivery', NULL),
+			('usps', '52', 'attempted_delivery', NULL),
+			('usps', '53', 'attempted_delivery', NULL),
+			('usps', '54', 'attempted_delivery', NULL),
+			('usps', '55', 'attempted_delivery', NULL),
+			('usps', '56', 'attempted_delivery', NULL),
+			('usps', '57', 'attempted_delivery', NULL),
+			('usps', '04', 'exception', 'Refused'),
+			('usps', '05', 'exception', 'Undeliverable as addressed'),
+			('usps', '21', 'exception', 'No such number'),
+			('usps', '22', 'exception', 'Insufficient address'),
+			('usps', '23', 'exception', 'Moved, left no address'),
+			('usps', '01', 'delivered', NULL),
+			('usps', '17', 'delivered', 'Picked up at the post office'),
+			('usps', '43', 'delivered', NULL),
+			('usps', '09', 'returned', NULL),
+			('usps', '27', 'returned', 'Unclaimed'),
+			('usps', '29', 'returned', NULL),
+			('usps', '31', 'returned', NULL),
+			('usps', '44', 'returned', 'Customer recall'),
+			('dhl', 'pre-transit', 'pending', NULL),
+			('dhl', 'transit', 'in_transit', NULL),
+			('dhl', 'delivered', 'delivered', NULL),
+			('dhl', 'failure', 'exception', NULL)
+		) AS v (carrier, code, status_key, description)
+		JOIN shipment_carriers c ON c.key = v.carrier
+		ON CONFLICT DO NOTHING`,
+	),
+	Down: exec(
+		`DROP TABLE IF EXISTS carrier_status_mappings`,
+		`DELETE FROM shipment_statuses WHERE key = 'awaiting_pickup'
+		AND NOT EXISTS (SELECT 1 FROM shipments WHERE shipments.status_id = shipment_statuses.id)`,
+	),
+}
@@ -0,0 +1,17 @@
+package migrations
+
+var addShipmentEventOffsets = Migration{
+	Version: 15,
+	Name:    "add_shipment_event_offsets",
+	Up: exec(
+		// occurred_at is stored in UTC; this keeps the scan's local offset so
+		// the event can be shown in the time zone it happened in. Events saved
+		// before this column existed have no offset.
+		`ALTER TABLE shipment_events
+			ADD COLUMN IF NOT EXISTS occurred_offset_seconds integer`,
+	),
+	Down: exec(
+		`ALTER TABLE shipment_events
+			DROP COLUMN IF EXISTS occurred_offset_seconds`,
+	),
+}
@@ -0,0 +1,26 @@
+package migrations
+
+var createDeferredNotifications = Migration{
+	Version: 16,
+	Name:    "create_deferred_notifications",
+	Up: exec(
+		`CREATE TABLE IF NOT EXISTS deferred_notifications (
+			id              bigserial PRIMARY KEY,
+			channel         varchar(50) NOT NULL,
+			subject         varchar(256) NOT NULL,
+			text            text NOT NULL,
+			html            text,
+			status          varchar(20) NOT NULL,
+			attempts        integer NOT NULL DEFAULT 0,
+			next_attempt_at timestamptz NOT NULL,
+			last_error      text,
+			created_at      timestamptz NOT NULL,
+			sent_at         timestamptz
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_deferred_notifications_due
+			ON deferred_notifications (next_attempt_at) WHERE status = 'pending'`,
+	),
+	Down: exec(
+		`DROP TABLE IF EXISTS deferred_notifications`,
+	),
+}
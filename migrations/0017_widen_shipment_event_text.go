@@ -0,0 +1,22 @@
+package migrations
+
+var widenShipmentEventText = Migration{
+	Version: 17,
+	Name:    "widen_shipment_event_text",
+	Up: exec(
+		// Carriers put free text in these, with no documented limit; a single
+		// value longer than the old column rolled back the whole save.
+		`ALTER TABLE shipment_events
+			ALTER COLUMN location TYPE text,
+			ALTER COLUMN description TYPE text`,
+		`ALTER TABLE shipments
+			ALTER COLUMN last_location TYPE text`,
+	),
+	Down: exec(
+		`ALTER TABLE shipment_events
+			ALTER COLUMN location TYPE varchar(100) USING left(location, 100),
+			ALTER COLUMN description TYPE varchar(256) USING left(description, 256)`,
+		`ALTER TABLE shipments
+			ALTER COLUMN last_location TYPE varchar(100) USING left(last_location, 100)`,
+	),
+}
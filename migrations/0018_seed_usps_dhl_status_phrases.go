@@ -0,0 +1,42 @@
+package migrations
+
+// The USPS status categories and DHL phrases were still compiled into their
+// processors, where they overrode carrier_status_mappings. They are seeded
+// under prefixed codes so they can be edited like any other mapping. A
+// package available for pickup is awaiting_pickup, as USPS code 16 is.
+var seedUspsDhlStatusPhrases = Migration{
+	Version: 18,
+	Name:    "seed_usps_dhl_status_phrases",
+	Up: exec(
+		`INSERT INTO carrier_status_mappings (carrier_id, code, status_key)
+		SELECT c.id, v.code, v.status_key
+		FROM (VALUES
+			('usps', 'category:pre-shipment', 'pending'),
+			('usps', 'category:accepted', 'accepted'),
+			('usps', 'category:in transit', 'in_transit'),
+			('usps', 'category:in-transit', 'in_transit'),
+			('usps', 'category:available for pickup', 'awaiting_pickup'),
+			('usps', 'category:out for delivery', 'out_for_delivery'),
+			('usps', 'category:delivery attempt', 'attempted_delivery'),
+			('usps', 'category:alert', 'exception'),
+			('usps', 'category:delayed', 'delayed'),
+			('usps', 'category:delivered', 'delivered'),
+			('usps', 'category:return to sender', 'returned'),
+			('dhl', 'phrase:returned to shipper', 'returned'),
+			('dhl', 'phrase:return to sender', 'returned'),
+			('dhl', 'phrase:out for delivery', 'out_for_delivery'),
+			('dhl', 'phrase:with delivery courier', 'out_for_delivery'),
+			('dhl', 'phrase:delivery attempted', 'attempted_delivery'),
+			('dhl', 'phrase:attempted delivery', 'attempted_delivery'),
+			('dhl', 'phrase:delay', 'delayed'),
+			('dhl', 'phrase:delayed', 'delayed'),
+			('dhl', 'phrase:picked up', 'accepted')
+		) AS v (carrier, code, status_key)
+		JOIN shipment_carriers c ON c.key = v.carrier
+		ON CONFLICT DO NOTHING`,
+	),
+	Down: exec(
+		`DELETE FROM carrier_status_mappings
+		WHERE code LIKE 'category:%' OR code LIKE 'phrase:%'`,
+	),
+}
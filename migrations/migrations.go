@@ -0,0 +1,23 @@
+package migrations
+
+// all lists every migration in the order it must be applied.
+var all = []Migration{
+	createShipmentReferenceTables,
+	createShipments,
+	createWorkerRuns,
+	seedShipmentStatuses,
+	seedShipmentCarriers,
+	addShipmentErrors,
+	addShipmentFailureTracking,
+	seedDetectedCarriers,
+	createShipmentEvents,
+	createWebhookDeliveries,
+	createDigests,
+	addShipmentHandoffTrackingNumber,
+	createCarrierScrapeDefinitions,
+	createCarrierStatusMappings,
+	addShipmentEventOffsets,
+	createDeferredNotifications,
+	widenShipmentEventText,
+	seedUspsDhlStatusPhrases,
+}
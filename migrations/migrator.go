@@ -0,0 +1,177 @@
+package migrations
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"time"
+)
+
+// Migration is a single, versioned schema change. Versions must be unique and
+// increasing; Down must undo exactly what Up did.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// SchemaMigration represents schema_migrations table
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:100;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+type Migrator struct {
+	logger     *zap.Logger
+	db         *gorm.DB
+	migrations []Migration
+}
+
+func NewMigrator(logger *zap.Logger, db *gorm.DB) *Migrator {
+	return &Migrator{logger, db, all}
+}
+
+// Up applies every pending migration in order, each in its own transaction.
+func (m *Migrator) Up() error {
+	if err := m.ensureTable(); err != nil {
+		return err
+	}
+
+	for _, migration := range m.migrations {
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := lock(tx); err != nil {
+				return err
+			}
+
+			applied, err := isApplied(tx, migration.Version)
+			if err != nil || applied {
+				return err
+			}
+
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+
+			return tx.Create(&SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+	}
+
+	version, err := m.Version()
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Database schema is up to date", zap.Int("version", version))
+	return nil
+}
+
+// Down rolls back the given number of most recently applied migrations.
+func (m *Migrator) Down(steps int) error {
+	if err := m.ensureTable(); err != nil {
+		return err
+	}
+
+	for i := 0; i < steps; i++ {
+		var rolledBack *Migration
+
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := lock(tx); err != nil {
+				return err
+			}
+
+			var latest SchemaMigration
+			result := tx.Order("version desc").Limit(1).Find(&latest)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			migration, ok := m.find(latest.Version)
+			if !ok {
+				return fmt.Errorf("no migration registered for applied version %d", latest.Version)
+			}
+
+			if err := migration.Down(tx); err != nil {
+				return fmt.Errorf("migration %d (%s) rollback failed: %w", migration.Version, migration.Name, err)
+			}
+
+			rolledBack = &migration
+			return tx.Delete(&SchemaMigration{}, latest.Version).Error
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if rolledBack == nil {
+			break
+		}
+
+		m.logger.Info("Migration rolled back",
+			zap.Int("version", rolledBack.Version),
+			zap.String("name", rolledBack.Name),
+		)
+	}
+
+	return nil
+}
+
+// Version returns the highest applied migration version, or 0 if none.
+func (m *Migrator) Version() (int, error) {
+	if err := m.ensureTable(); err != nil {
+		return 0, err
+	}
+
+	var version int
+	err := m.db.Model(&SchemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+func (m *Migrator) ensureTable() error {
+	return m.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    integer PRIMARY KEY,
+		name       varchar(100) NOT NULL,
+		applied_at timestamptz NOT NULL
+	)`).Error
+}
+
+func (m *Migrator) find(version int) (Migration, bool) {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// lock serializes migrations across replicas for the rest of the transaction.
+func lock(tx *gorm.DB) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(hashtext('schema_migrations'))").Error
+}
+
+func isApplied(tx *gorm.DB, version int) (bool, error) {
+	var count int64
+	err := tx.Model(&SchemaMigration{}).Where("version = ?", version).Count(&count).Error
+	return count > 0, err
+}
+
+// exec returns a migration step that runs each statement in order.
+func exec(statements ...string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,156 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"personal-homepage-service/core"
+	"personal-homepage-service/notifications/models"
+	"personal-homepage-service/notifications/repositories"
+	"time"
+)
+
+const (
+	deferredBatchSize      = 100
+	deferredMaxAttempts    = 5
+	deferredRetryBaseDelay = time.Minute
+	deferredRetryMaxDelay  = time.Hour
+)
+
+// Queue stores msg for every channel within tx, to be sent by SendDeferred on
+// its next run or, during quiet hours, once they are over. Handlers on the
+// shipment save path queue messages instead of calling Send so that a slow
+// channel never holds up the save.
+func (d *Dispatcher) Queue(tx *gorm.DB, msg Message) error {
+	at := d.now()
+	if d.Quiet(at) {
+		at = d.quietEnd(at)
+	}
+	return d.hold(repositories.NewRepository(tx), msg, at)
+}
+
+// hold stores msg for every channel in repo so it can be sent from until on,
+// such as once quiet hours end instead of waking anyone up.
+func (d *Dispatcher) hold(repo *repositories.Repository, msg Message, until time.Time) error {
+	if len(d.notifiers) == 0 {
+		return nil
+	}
+
+	now := d.now().UTC()
+	deferred := make([]models.DeferredNotification, 0, len(d.notifiers))
+	for _, n := range d.notifiers {
+		deferred = append(deferred, models.DeferredNotification{
+			Channel:       n.Name(),
+			Subject:       msg.Subject,
+			Text:          msg.Text,
+			HTML:          msg.HTML,
+			Status:        models.DeferredStatusPending,
+			NextAttemptAt: until.UTC(),
+			CreatedAt:     now,
+		})
+	}
+
+	if err := repo.CreateDeferred(deferred); err != nil {
+		d.logger.Error("Failed to defer notification",
+			zap.String("subject", msg.Subject),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to defer notification: %w", err)
+	}
+
+	d.logger.Info("Notification deferred",
+		zap.String("subject", msg.Subject),
+		zap.Time("until", until),
+	)
+	return nil
+}
+
+// SendDeferred delivers queued messages and those held back during quiet
+// hours, retrying
+// failed ones with backoff until they succeed or run out of attempts. Nothing
+// is sent while quiet hours are still in effect.
+func (d *Dispatcher) SendDeferred(ctx context.Context) error {
+	now := d.now()
+	if d.Quiet(now) {
+		return nil
+	}
+
+	deferred, err := d.repo.GetDueDeferred(now.UTC(), deferredBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to load deferred notifications: %w", err)
+	}
+
+	for _, n := range deferred {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		d.sendDeferred(ctx, &n)
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) sendDeferred(ctx context.Context, n *models.DeferredNotification) {
+	counters := core.CountersFromContext(ctx)
+	now := d.now().UTC()
+	n.Attempts++
+
+	notifier := d.notifier(n.Channel)
+
+	var err error
+	if notifier == nil {
+		err = fmt.Errorf("channel %q is not configured", n.Channel)
+	} else {
+		err = notifier.Notify(ctx, Message{Subject: n.Subject, Text: n.Text, HTML: n.HTML})
+	}
+	if err != nil && ctx.Err() != nil {
+		// Leave the notification untouched so the next run picks it up again.
+		return
+	}
+
+	switch {
+	case err == nil:
+		n.Status = models.DeferredStatusSent
+		n.SentAt = &now
+		n.LastError = ""
+		counters.Add("notifications_sent", 1)
+	case notifier == nil || n.Attempts >= deferredMaxAttempts:
+		n.Status = models.DeferredStatusFailed
+		n.LastError = err.Error()
+		counters.Add("notifications_failed", 1)
+		d.logger.Error("Deferred notification failed, giving up",
+			zap.Uint("notification_id", n.ID),
+			zap.String("channel", n.Channel),
+			zap.Int("attempts", n.Attempts),
+			zap.Error(err),
+		)
+	default:
+		n.NextAttemptAt = now.Add(core.Backoff(n.Attempts, deferredRetryBaseDelay, deferredRetryMaxDelay))
+		n.LastError = err.Error()
+		counters.Add("notifications_retried", 1)
+		d.logger.Warn("Deferred notification failed, will retry",
+			zap.Uint("notification_id", n.ID),
+			zap.String("channel", n.Channel),
+			zap.Int("attempts", n.Attempts),
+			zap.Time("next_attempt_at", n.NextAttemptAt),
+			zap.Error(err),
+		)
+	}
+
+	if err := d.repo.SaveDeferred(n); err != nil {
+		d.logger.Error("Failed to save deferred notification",
+			zap.Uint("notification_id", n.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (d *Dispatcher) notifier(channel string) Notifier {
+	for _, n := range d.notifiers {
+		if n.Name() == channel {
+			return n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"personal-homepage-service/config"
+	"personal-homepage-service/notifications"
+	"strings"
+	"testing"
+	"time"
+)
+
+// envelope is what the stub SMTP server received in one session.
+type envelope struct {
+	from string
+	to   []string
+	data string
+}
+
+// serveSMTP accepts a single session on l, speaking just enough SMTP for
+// Sender, and reports what it received.
+func serveSMTP(t *testing.T, l net.Listener) <-chan envelope {
+	t.Helper()
+	received := make(chan envelope, 1)
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) {
+			_, _ = io.WriteString(conn, line+"\r\n")
+		}
+
+		var env envelope
+		reply("220 stub ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+
+			switch verb {
+			case "EHLO", "HELO":
+				reply("250-stub")
+				reply("250 8BITMIME")
+			case "MAIL":
+				env.from = pathOf(line)
+				reply("250 OK")
+			case "RCPT":
+				env.to = append(env.to, pathOf(line))
+				reply("250 OK")
+			case "DATA":
+				reply("354 Go ahead")
+				var data strings.Builder
+				for {
+					dl, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if dl == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(dl, "."))
+				}
+				env.data = data.String()
+				reply("250 OK")
+			case "QUIT":
+				reply("221 Bye")
+				received <- env
+				return
+			default:
+				reply("502 Not implemented")
+			}
+		}
+	}()
+
+	return received
+}
+
+// pathOf returns the address between angle brackets in a MAIL or RCPT
+// command.
+func pathOf(line string) string {
+	_, rest, _ := strings.Cut(line, "<")
+	addr, _, _ := strings.Cut(rest, ">")
+	return addr
+}
+
+func TestSenderNotify(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	received := serveSMTP(t, l)
+
+	cfg := &config.SmtpConfig{
+		Host: "127.0.0.1",
+		Port: l.Addr().(*net.TCPAddr).Port,
+		From: "Shipments <shipments@example.com>",
+		To:   []string{"me@example.com", "Partner <partner@example.com>"},
+	}
+	msg := notifications.Message{
+		Subject: "Lamp was delivered ✓",
+		Text:    "Your lamp was delivered.\nTracking: 1Z999AA10123456784",
+		HTML:    `<p>Your lamp was <strong>delivered</strong>.</p>`,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := NewSender(cfg).Notify(ctx, msg); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	var env envelope
+	select {
+	case env = <-received:
+	case <-ctx.Done():
+		t.Fatal("stub server received no message")
+	}
+
+	if env.from != "shipments@example.com" {
+		t.Errorf("MAIL FROM = %q, want %q", env.from, "shipments@example.com")
+	}
+	wantTo := []string{"me@example.com", "partner@example.com"}
+	if strings.Join(env.to, ",") != strings.Join(wantTo, ",") {
+		t.Errorf("RCPT TO = %v, want %v", env.to, wantTo)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(env.data))
+	if err != nil {
+		t.Fatalf("ReadMessage() error = %v", err)
+	}
+
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Subject = %q (%v), want %q", subject, err, msg.Subject)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q (%v), want multipart/alternative", m.Header.Get("Content-Type"), err)
+	}
+
+	bodies := make(map[string]string)
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		partType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		content, err := io.ReadAll(part)
+		if err != nil {
+			t.Fatalf("reading %s part: %v", partType, err)
+		}
+		// Text is sent with CRLF line endings, as mail requires.
+		bodies[partType] = strings.ReplaceAll(string(content), "\r\n", "\n")
+	}
+
+	if got := bodies["text/plain"]; got != msg.Text {
+		t.Errorf("text/plain body = %q, want %q", got, msg.Text)
+	}
+	if got := bodies["text/html"]; got != msg.HTML {
+		t.Errorf("text/html body = %q, want %q", got, msg.HTML)
+	}
+}
@@ -0,0 +1,105 @@
+package notifications
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"personal-homepage-service/workers/shipments"
+	"personal-homepage-service/workers/shipments/models"
+	"slices"
+	"time"
+)
+
+type statusChangedData struct {
+	Label          string
+	TrackingNumber string
+	TrackingURL    string
+	Carrier        string
+	PreviousStatus string
+	Status         string
+	Location       string
+	DeliveryWindow string
+}
+
+// Milestones notifies people when a shipment reaches one of the configured
+// statuses.
+type Milestones struct {
+	logger     *zap.Logger
+	dispatcher *Dispatcher
+}
+
+func NewMilestones(logger *zap.Logger, dispatcher *Dispatcher) *Milestones {
+	return &Milestones{logger: logger, dispatcher: dispatcher}
+}
+
+// HandleStatusChanged queues a notification within tx, the transaction saving
+// the shipment, when the new status is one people asked to hear about. A
+// message that fails to render is logged rather than failing the save.
+func (m *Milestones) HandleStatusChanged(tx *gorm.DB, e shipments.StatusChanged) error {
+	if !slices.Contains(m.dispatcher.cfg.Notifications.Statuses, e.Status) {
+		return nil
+	}
+
+	msg, err := Render("status_changed", subjectFor(e), newStatusChangedData(e, m.dispatcher.cfg.Notifications.Location))
+	if err != nil {
+		m.logger.Error("Failed to render notification",
+			zap.String("tracking_number", e.Shipment.TrackingNumber),
+			zap.Error(err),
+		)
+		return nil
+	}
+
+	return m.dispatcher.Queue(tx, msg)
+}
+
+func subjectFor(e shipments.StatusChanged) string {
+	switch e.Status {
+	case "delivered":
+		return fmt.Sprintf("%s was delivered", e.Shipment.Label)
+	case "exception":
+		return fmt.Sprintf("%s has a delivery exception", e.Shipment.Label)
+	case "delayed":
+		return fmt.Sprintf("%s is delayed", e.Shipment.Label)
+	}
+	return fmt.Sprintf("%s: %s", e.Shipment.Label, statusLabel(e.Shipment.Status, e.Status))
+}
+
+func newStatusChangedData(e shipments.StatusChanged, loc *time.Location) statusChangedData {
+	sh := e.Shipment
+	data := statusChangedData{
+		Label:          sh.Label,
+		TrackingNumber: sh.TrackingNumber,
+		TrackingURL:    sh.TrackingURL,
+		PreviousStatus: e.PreviousStatus,
+		Status:         statusLabel(sh.Status, e.Status),
+		Location:       sh.LastLocation,
+		DeliveryWindow: FormatWindow(sh.DeliveryWindowStart, sh.DeliveryWindowEnd, loc),
+	}
+	if sh.Carrier != nil {
+		data.Carrier = sh.Carrier.Label
+	}
+	return data
+}
+
+func statusLabel(status *models.ShipmentStatus, key string) string {
+	if status != nil && status.Label != "" {
+		return status.Label
+	}
+	return key
+}
+
+// FormatWindow describes a delivery window in loc, collapsing it to a single
+// time when only one end is known.
+func FormatWindow(start *time.Time, end *time.Time, loc *time.Location) string {
+	const layout = "Mon Jan 2, 3:04 PM"
+
+	switch {
+	case start != nil && end != nil && !start.Equal(*end):
+		return fmt.Sprintf("%s – %s", start.In(loc).Format(layout), end.In(loc).Format(layout))
+	case end != nil:
+		return end.In(loc).Format(layout)
+	case start != nil:
+		return start.In(loc).Format(layout)
+	}
+	return ""
+}
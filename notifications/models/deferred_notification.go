@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	DeferredStatusPending = "pending"
+	DeferredStatusSent    = "sent"
+	DeferredStatusFailed  = "failed"
+)
+
+// DeferredNotification represents deferred_notifications table, a message
+// held back during quiet hours, one row per channel it still has to go to.
+type DeferredNotification struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	Channel       string    `gorm:"size:50;not null"`
+	Subject       string    `gorm:"size:256;not null"`
+	Text          string    `gorm:"type:text;not null"`
+	HTML          string    `gorm:"type:text"`
+	Status        string    `gorm:"size:20;not null"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null"`
+	LastError     string    `gorm:"type:text"`
+	CreatedAt     time.Time `gorm:"not null"`
+	SentAt        *time.Time
+}
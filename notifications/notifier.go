@@ -0,0 +1,106 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"personal-homepage-service/config"
+	"personal-homepage-service/notifications/repositories"
+	"time"
+)
+
+// Message is a notification rendered for people, with a plain-text body and an
+// optional HTML alternative.
+type Message struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Notifier delivers messages over a single channel such as email.
+type Notifier interface {
+	Name() string
+	Notify(ctx context.Context, msg Message) error
+}
+
+// Dispatcher sends messages through every configured channel, holding them
+// back during quiet hours.
+type Dispatcher struct {
+	logger    *zap.Logger
+	cfg       *config.Config
+	repo      *repositories.Repository
+	notifiers []Notifier
+	now       func() time.Time
+}
+
+func NewDispatcher(logger *zap.Logger, cfg config.Config, db *gorm.DB, notifiers ...Notifier) *Dispatcher {
+	return &Dispatcher{
+		logger:    logger,
+		cfg:       &cfg,
+		repo:      repositories.NewRepository(db),
+		notifiers: notifiers,
+		now:       time.Now,
+	}
+}
+
+// Enabled reports whether any channel is configured.
+func (d *Dispatcher) Enabled() bool {
+	return len(d.notifiers) > 0
+}
+
+// Send delivers msg through every channel, returning how many channels
+// accepted it and the joined errors of those that failed. Messages sent during
+// quiet hours are stored and delivered by SendDeferred once quiet hours are
+// over, and count as not sent.
+func (d *Dispatcher) Send(ctx context.Context, msg Message) (int, error) {
+	if now := d.now(); d.Quiet(now) {
+		return 0, d.hold(d.repo, msg, d.quietEnd(now))
+	}
+
+	sent := 0
+	var errs []error
+	for _, n := range d.notifiers {
+		if err := n.Notify(ctx, msg); err != nil {
+			d.logger.Error("Failed to send notification",
+				zap.String("channel", n.Name()),
+				zap.String("subject", msg.Subject),
+				zap.Error(err),
+			)
+			errs = append(errs, err)
+			continue
+		}
+		sent++
+	}
+
+	return sent, errors.Join(errs...)
+}
+
+// Quiet reports whether t falls within the configured quiet hours.
+func (d *Dispatcher) Quiet(t time.Time) bool {
+	quiet := d.cfg.Notifications.QuietHours
+	if quiet == nil || quiet.Start == quiet.End {
+		return false
+	}
+
+	t = t.In(d.cfg.Notifications.Location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+
+	if quiet.Start < quiet.End {
+		return offset >= quiet.Start && offset < quiet.End
+	}
+	return offset >= quiet.Start || offset < quiet.End
+}
+
+// quietEnd returns when the quiet hours t falls within are over.
+func (d *Dispatcher) quietEnd(t time.Time) time.Time {
+	t = t.In(d.cfg.Notifications.Location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+
+	end := midnight.Add(d.cfg.Notifications.QuietHours.End)
+	if !end.After(t) {
+		end = midnight.AddDate(0, 0, 1).Add(d.cfg.Notifications.QuietHours.End)
+	}
+	return end
+}
@@ -0,0 +1,138 @@
+package notifications
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"personal-homepage-service/config"
+	"testing"
+	"time"
+)
+
+type recordingNotifier struct {
+	sent []Message
+}
+
+func (n *recordingNotifier) Name() string { return "recording" }
+
+func (n *recordingNotifier) Notify(_ context.Context, msg Message) error {
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+func newTestDispatcher(quiet *config.QuietHours, loc *time.Location, notifiers ...Notifier) *Dispatcher {
+	cfg := config.Config{
+		Notifications: &config.NotificationsConfig{
+			QuietHours: quiet,
+			Location:   loc,
+		},
+	}
+	return NewDispatcher(zap.NewNop(), cfg, nil, notifiers...)
+}
+
+func TestDispatcherQuiet(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name    string
+		quiet   *config.QuietHours
+		now     time.Time
+		want    bool
+		wantEnd time.Time
+	}{
+		{
+			name:  "no quiet hours",
+			quiet: nil,
+			now:   at(4, 23, 0),
+			want:  false,
+		},
+		{
+			name:  "empty window",
+			quiet: &config.QuietHours{Start: 22 * time.Hour, End: 22 * time.Hour},
+			now:   at(4, 22, 0),
+			want:  false,
+		},
+		{
+			name:    "inside same-day window",
+			quiet:   &config.QuietHours{Start: 13 * time.Hour, End: 14 * time.Hour},
+			now:     at(4, 13, 30),
+			want:    true,
+			wantEnd: at(4, 14, 0),
+		},
+		{
+			name:  "end of same-day window is not quiet",
+			quiet: &config.QuietHours{Start: 13 * time.Hour, End: 14 * time.Hour},
+			now:   at(4, 14, 0),
+			want:  false,
+		},
+		{
+			name:  "before window wrapping midnight",
+			quiet: &config.QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour},
+			now:   at(4, 21, 59),
+			want:  false,
+		},
+		{
+			name:    "start of window wrapping midnight",
+			quiet:   &config.QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour},
+			now:     at(4, 22, 0),
+			want:    true,
+			wantEnd: at(5, 7, 0),
+		},
+		{
+			name:    "after midnight in window wrapping midnight",
+			quiet:   &config.QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour},
+			now:     at(5, 3, 0),
+			want:    true,
+			wantEnd: at(5, 7, 0),
+		},
+		{
+			name:  "end of window wrapping midnight",
+			quiet: &config.QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour},
+			now:   at(5, 7, 0),
+			want:  false,
+		},
+		{
+			name:    "other zone converted to configured location",
+			quiet:   &config.QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour},
+			now:     time.Date(2024, time.March, 5, 4, 0, 0, 0, time.UTC),
+			want:    true,
+			wantEnd: at(5, 7, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newTestDispatcher(tt.quiet, loc)
+			d.now = func() time.Time { return tt.now }
+
+			if got := d.Quiet(d.now()); got != tt.want {
+				t.Fatalf("Quiet(%s) = %v, want %v", tt.now, got, tt.want)
+			}
+			if !tt.want {
+				return
+			}
+			if got := d.quietEnd(d.now()); !got.Equal(tt.wantEnd) {
+				t.Errorf("quietEnd(%s) = %s, want %s", tt.now, got, tt.wantEnd)
+			}
+		})
+	}
+}
+
+func TestDispatcherSendOutsideQuietHours(t *testing.T) {
+	loc := time.FixedZone("EST", -5*60*60)
+	notifier := &recordingNotifier{}
+	d := newTestDispatcher(&config.QuietHours{Start: 22 * time.Hour, End: 7 * time.Hour}, loc, notifier)
+	d.now = func() time.Time { return time.Date(2024, time.March, 4, 12, 0, 0, 0, loc) }
+
+	msg := Message{Subject: "Lamp was delivered", Text: "Your lamp was delivered."}
+	sent, err := d.Send(context.Background(), msg)
+	if err != nil || sent != 1 {
+		t.Fatalf("Send() = %d, %v, want 1, nil", sent, err)
+	}
+
+	if len(notifier.sent) != 1 || notifier.sent[0] != msg {
+		t.Errorf("notifier received %v, want [%v]", notifier.sent, msg)
+	}
+}
@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"personal-homepage-service/notifications/models"
+	"time"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) CreateDeferred(notifications []models.DeferredNotification) error {
+	return r.db.Create(&notifications).Error
+}
+
+// GetDueDeferred returns pending notifications whose next attempt is due,
+// oldest first.
+func (r *Repository) GetDueDeferred(now time.Time, limit int) ([]models.DeferredNotification, error) {
+	var notifications []models.DeferredNotification
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.DeferredStatusPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&notifications).Error
+	return notifications, err
+}
+
+func (r *Repository) SaveDeferred(notification *models.DeferredNotification) error {
+	return r.db.Save(notification).Error
+}
@@ -0,0 +1,51 @@
+package deferred
+
+import (
+	"context"
+	"go.uber.org/zap"
+	"personal-homepage-service/core"
+	"personal-homepage-service/notifications"
+	"sync/atomic"
+	"time"
+)
+
+// Worker sends queued notifications, including those held back during quiet
+// hours once they are over.
+type Worker struct {
+	logger     *zap.Logger
+	dispatcher *notifications.Dispatcher
+	busy       atomic.Bool
+}
+
+func NewWorker(logger *zap.Logger, dispatcher *notifications.Dispatcher) *Worker {
+	return &Worker{
+		logger:     logger,
+		dispatcher: dispatcher,
+	}
+}
+
+func (w *Worker) Name() string {
+	return "deferred_notifications"
+}
+
+func (w *Worker) Schedule() string {
+	return "* * * * *"
+}
+
+func (w *Worker) Ready(time.Time) bool {
+	return !w.busy.Load()
+}
+
+func (w *Worker) Execute(ctx context.Context) error {
+	if !w.busy.CompareAndSwap(false, true) {
+		return core.ErrWorkerBusy
+	}
+	defer w.busy.Store(false)
+
+	if !w.dispatcher.Enabled() {
+		w.logger.Info("No notification channels configured. Deferred notifications skipped 😴")
+		return nil
+	}
+
+	return w.dispatcher.SendDeferred(ctx)
+}
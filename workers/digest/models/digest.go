@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Digest represents digests table, one row per digest sent.
+type Digest struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement"`
+	SentAt      time.Time `gorm:"not null;index"`
+	Fingerprint string    `gorm:"size:64;not null"`
+	Arriving    int       `gorm:"not null;default:0"`
+	Late        int       `gorm:"not null;default:0"`
+	Delivered   int       `gorm:"not null;default:0"`
+}
@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"personal-homepage-service/workers/digest/models"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+// GetLastDigest returns the most recently sent digest, or nil if none was
+// ever sent.
+func (r *Repository) GetLastDigest() (*models.Digest, error) {
+	var digest models.Digest
+	err := r.db.Order("sent_at desc").First(&digest).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &digest, nil
+}
+
+func (r *Repository) CreateDigest(digest *models.Digest) error {
+	return r.db.Create(digest).Error
+}
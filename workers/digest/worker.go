@@ -0,0 +1,271 @@
+package digest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"personal-homepage-service/config"
+	"personal-homepage-service/core"
+	"personal-homepage-service/notifications"
+	"personal-homepage-service/workers/digest/models"
+	"personal-homepage-service/workers/digest/repositories"
+	shipmentmodels "personal-homepage-service/workers/shipments/models"
+	shipmentrepositories "personal-homepage-service/workers/shipments/repositories"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// firstDigestLookback bounds "delivered since the last digest" when no digest
+// has been sent yet.
+const firstDigestLookback = 24 * time.Hour
+
+type item struct {
+	ID             uint
+	Label          string
+	TrackingNumber string
+	TrackingURL    string
+	Carrier        string
+	Status         string
+	Location       string
+	Expected       string
+}
+
+type digestData struct {
+	Date      string
+	Arriving  []item
+	Late      []item
+	Delivered []item
+}
+
+func (d digestData) empty() bool {
+	return len(d.Arriving) == 0 && len(d.Late) == 0 && len(d.Delivered) == 0
+}
+
+// Worker sends a periodic summary of open shipments through the notification
+// channels, instead of a message per change.
+type Worker struct {
+	logger     *zap.Logger
+	cfg        *config.Config
+	dispatcher *notifications.Dispatcher
+	repo       *repositories.Repository
+	shipments  *shipmentrepositories.Repository
+	busy       atomic.Bool
+}
+
+func NewWorker(logger *zap.Logger, cfg config.Config, db *gorm.DB, dispatcher *notifications.Dispatcher) *Worker {
+	return &Worker{
+		logger:     logger,
+		cfg:        &cfg,
+		dispatcher: dispatcher,
+		repo:       repositories.NewRepository(db),
+		shipments:  shipmentrepositories.NewRepository(db),
+	}
+}
+
+func (w *Worker) Name() string {
+	return "digest"
+}
+
+func (w *Worker) Schedule() string {
+	return w.cfg.Notifications.DigestSchedule
+}
+
+func (w *Worker) Ready(time.Time) bool {
+	return !w.busy.Load()
+}
+
+// Validate rejects a schedule that runs inside quiet hours, where the digest
+// would be skipped every time. Quiet hours do not matter when there is no
+// channel to send the digest through.
+func (w *Worker) Validate() error {
+	schedule, err := cron.ParseStandard(w.Schedule())
+	if err != nil {
+		return fmt.Errorf("invalid digest schedule %q: %w", w.Schedule(), err)
+	}
+
+	if !w.dispatcher.Enabled() {
+		return nil
+	}
+
+	// A week of runs covers every daily and weekly schedule.
+	now := time.Now()
+	for next := schedule.Next(now); !next.IsZero() && next.Sub(now) <= 7*24*time.Hour; next = schedule.Next(next) {
+		if w.dispatcher.Quiet(next) {
+			return fmt.Errorf("digest schedule %q runs at %s, inside quiet hours",
+				w.Schedule(), next.In(w.cfg.Notifications.Location).Format("Mon 15:04 MST"))
+		}
+	}
+	return nil
+}
+
+func (w *Worker) Execute(ctx context.Context) error {
+	if !w.busy.CompareAndSwap(false, true) {
+		return core.ErrWorkerBusy
+	}
+	defer w.busy.Store(false)
+
+	if !w.dispatcher.Enabled() {
+		w.logger.Info("No notification channels configured. Digest skipped 😴")
+		return nil
+	}
+
+	now := time.Now()
+	if w.dispatcher.Quiet(now) {
+		w.logger.Info("Digest skipped during quiet hours")
+		return nil
+	}
+
+	last, err := w.repo.GetLastDigest()
+	if err != nil {
+		return fmt.Errorf("failed to load last digest: %w", err)
+	}
+
+	since := now.Add(-firstDigestLookback)
+	if last != nil {
+		since = last.SentAt
+	}
+
+	data, err := w.build(now, since)
+	if err != nil {
+		return err
+	}
+
+	counters := core.CountersFromContext(ctx)
+	counters.Add("digest_arriving", len(data.Arriving))
+	counters.Add("digest_late", len(data.Late))
+	counters.Add("digest_delivered", len(data.Delivered))
+
+	if data.empty() {
+		w.logger.Info("Nothing to report. Digest skipped 😴")
+		return nil
+	}
+
+	fp := fingerprint(data)
+	if last != nil && last.Fingerprint == fp {
+		w.logger.Info("Nothing changed since the last digest. Digest skipped 😴")
+		return nil
+	}
+
+	msg, err := notifications.Render("digest", "Shipment digest for "+data.Date, data)
+	if err != nil {
+		return fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	// Once any channel has it the digest counts as sent, so a channel that is
+	// down does not make the others receive it again on the next run.
+	sent, err := w.dispatcher.Send(ctx, msg)
+	if sent == 0 && err != nil {
+		return fmt.Errorf("failed to send digest: %w", err)
+	}
+	if err != nil {
+		w.logger.Warn("Digest not sent on every channel", zap.Error(err))
+	}
+
+	digest := &models.Digest{
+		SentAt:      now.UTC(),
+		Fingerprint: fp,
+		Arriving:    len(data.Arriving),
+		Late:        len(data.Late),
+		Delivered:   len(data.Delivered),
+	}
+	if err := w.repo.CreateDigest(digest); err != nil {
+		return fmt.Errorf("failed to record digest: %w", err)
+	}
+
+	w.logger.Info("Digest sent",
+		zap.Int("arriving", digest.Arriving),
+		zap.Int("late", digest.Late),
+		zap.Int("delivered", digest.Delivered),
+	)
+	return nil
+}
+
+// build sorts open shipments into arriving today and late against their
+// delivery window end, and collects those delivered since the last digest.
+func (w *Worker) build(now time.Time, since time.Time) (digestData, error) {
+	loc := w.cfg.Notifications.Location
+	today := now.In(loc)
+	data := digestData{Date: today.Format("Monday, January 2")}
+
+	open, err := w.shipments.GetOpenShipments()
+	if err != nil {
+		return data, fmt.Errorf("failed to load open shipments: %w", err)
+	}
+
+	for _, sh := range open {
+		end := sh.DeliveryWindowEnd
+		if end == nil {
+			end = sh.DeliveryWindowStart
+		}
+		if end == nil {
+			continue
+		}
+
+		switch {
+		case end.Before(now):
+			data.Late = append(data.Late, w.toItem(sh))
+		case sameDay(end.In(loc), today):
+			data.Arriving = append(data.Arriving, w.toItem(sh))
+		}
+	}
+
+	delivered, err := w.shipments.GetShipmentsDeliveredSince(since)
+	if err != nil {
+		return data, fmt.Errorf("failed to load delivered shipments: %w", err)
+	}
+
+	for _, sh := range delivered {
+		data.Delivered = append(data.Delivered, w.toItem(sh))
+	}
+
+	return data, nil
+}
+
+func (w *Worker) toItem(sh shipmentmodels.Shipment) item {
+	it := item{
+		ID:             sh.ID,
+		Label:          sh.Label,
+		TrackingNumber: sh.TrackingNumber,
+		TrackingURL:    sh.TrackingURL,
+		Location:       sh.LastLocation,
+		Expected:       notifications.FormatWindow(sh.DeliveryWindowStart, sh.DeliveryWindowEnd, w.cfg.Notifications.Location),
+	}
+	if sh.Status != nil {
+		it.Status = sh.Status.Label
+	}
+	if sh.Carrier != nil {
+		it.Carrier = sh.Carrier.Label
+	}
+	return it
+}
+
+// fingerprint identifies the content of a digest so an unchanged one is not
+// sent twice. The date is left out on purpose.
+func fingerprint(data digestData) string {
+	var lines []string
+	for section, items := range map[string][]item{
+		"arriving":  data.Arriving,
+		"late":      data.Late,
+		"delivered": data.Delivered,
+	} {
+		for _, it := range items {
+			lines = append(lines, fmt.Sprintf("%s:%d:%s:%s:%s", section, it.ID, it.Status, it.Expected, it.Location))
+		}
+	}
+	sort.Strings(lines)
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
@@ -0,0 +1,27 @@
+package shipments
+
+import (
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"personal-homepage-service/workers/shipments/processors/dhl"
+	"personal-homepage-service/workers/shipments/processors/fedex"
+	"personal-homepage-service/workers/shipments/processors/scrape"
+	"personal-homepage-service/workers/shipments/processors/uds"
+	"personal-homepage-service/workers/shipments/processors/unsupported"
+	"personal-homepage-service/workers/shipments/processors/ups"
+	"personal-homepage-service/workers/shipments/processors/usps"
+)
+
+// NewRegistry returns a registry of every carrier this service can track,
+// including those scraped according to definitions. Shipments whose carrier
+// is not listed fall back to the unsupported processor.
+func NewRegistry(definitions []models.CarrierScrapeDefinition, statuses *processors.StatusMapper) *processors.Registry {
+	r := processors.NewRegistry(unsupported.Factory, statuses)
+	scrape.Register(r, definitions)
+	ups.Register(r)
+	fedex.Register(r)
+	usps.Register(r)
+	dhl.Register(r)
+	uds.Register(r)
+	return r
+}
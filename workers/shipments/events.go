@@ -0,0 +1,111 @@
+package shipments
+
+import (
+	"personal-homepage-service/core"
+	"personal-homepage-service/workers/shipments/models"
+	"time"
+)
+
+const (
+	EventStatusChanged         = "shipments.status_changed"
+	EventDeliveryWindowChanged = "shipments.delivery_window_changed"
+	EventLocationChanged       = "shipments.location_changed"
+	EventDelivered             = "shipments.delivered"
+	EventExceptionRaised       = "shipments.exception_raised"
+)
+
+// exceptionStatuses need someone's attention and raise ExceptionRaised when a
+// shipment enters them.
+var exceptionStatuses = map[string]bool{
+	"exception":          true,
+	"attempted_delivery": true,
+	"returned":           true,
+}
+
+// StatusChanged is published whenever a shipment's status key changes.
+type StatusChanged struct {
+	Shipment       models.Shipment
+	PreviousStatus string
+	Status         string
+}
+
+func (StatusChanged) EventName() string { return EventStatusChanged }
+
+type DeliveryWindowChanged struct {
+	Shipment      models.Shipment
+	PreviousStart *time.Time
+	PreviousEnd   *time.Time
+	Start         *time.Time
+	End           *time.Time
+}
+
+func (DeliveryWindowChanged) EventName() string { return EventDeliveryWindowChanged }
+
+type LocationChanged struct {
+	Shipment         models.Shipment
+	PreviousLocation string
+	Location         string
+}
+
+func (LocationChanged) EventName() string { return EventLocationChanged }
+
+// Delivered is published once, when a shipment first reaches delivered.
+type Delivered struct {
+	Shipment models.Shipment
+}
+
+func (Delivered) EventName() string { return EventDelivered }
+
+type ExceptionRaised struct {
+	Shipment models.Shipment
+	Status   string
+}
+
+func (ExceptionRaised) EventName() string { return EventExceptionRaised }
+
+// diffShipment returns the events describing how before became after.
+func diffShipment(before models.Shipment, after models.Shipment) []core.Event {
+	var events []core.Event
+
+	prevStatus, status := statusKey(before), statusKey(after)
+	if prevStatus != status {
+		events = append(events, StatusChanged{after, prevStatus, status})
+
+		if status == "delivered" {
+			events = append(events, Delivered{after})
+		}
+		if exceptionStatuses[status] {
+			events = append(events, ExceptionRaised{after, status})
+		}
+	}
+
+	if !sameTime(before.DeliveryWindowStart, after.DeliveryWindowStart) || !sameTime(before.DeliveryWindowEnd, after.DeliveryWindowEnd) {
+		events = append(events, DeliveryWindowChanged{
+			Shipment:      after,
+			PreviousStart: before.DeliveryWindowStart,
+			PreviousEnd:   before.DeliveryWindowEnd,
+			Start:         after.DeliveryWindowStart,
+			End:           after.DeliveryWindowEnd,
+		})
+	}
+
+	if before.LastLocation != after.LastLocation && after.LastLocation != "" {
+		events = append(events, LocationChanged{after, before.LastLocation, after.LastLocation})
+	}
+
+	return events
+}
+
+func statusKey(sh models.Shipment) string {
+	if sh.Status == nil {
+		return ""
+	}
+	return sh.Status.Key
+}
+
+func sameTime(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
@@ -0,0 +1,24 @@
+package models
+
+// CarrierScrapeDefinition represents carrier_scrape_definitions table. It
+// describes how to read a carrier's tracking page:
+//
+//   - URLTemplate is the page address, with {tracking_number} substituted.
+//   - The selectors are CSS selectors. Location and ETA are optional, and
+//     their patterns, when set, are regular expressions whose first capture
+//     group is used instead of the whole element text.
+//   - DateLayouts are Go time layouts tried in order to parse the ETA.
+//   - StatusMap maps status text, matched case-insensitively, to a status key.
+type CarrierScrapeDefinition struct {
+	ID               uint              `gorm:"primaryKey;autoIncrement"`
+	CarrierID        uint              `gorm:"not null;unique"`
+	Carrier          *ShipmentCarrier  `gorm:"foreignKey:CarrierID;references:ID"`
+	URLTemplate      string            `gorm:"size:512;not null"`
+	StatusSelector   string            `gorm:"size:256;not null"`
+	LocationSelector string            `gorm:"size:256"`
+	LocationPattern  string            `gorm:"size:256"`
+	ETASelector      string            `gorm:"column:eta_selector;size:256"`
+	ETAPattern       string            `gorm:"column:eta_pattern;size:256"`
+	DateLayouts      []string          `gorm:"type:jsonb;serializer:json"`
+	StatusMap        map[string]string `gorm:"type:jsonb;serializer:json"`
+}
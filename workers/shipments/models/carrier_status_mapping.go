@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// CarrierStatusMapping represents carrier_status_mappings table. A nil
+// StatusKey marks a code seen from the carrier that has not been mapped yet.
+type CarrierStatusMapping struct {
+	ID          uint             `gorm:"primaryKey;autoIncrement"`
+	CarrierID   uint             `gorm:"not null;uniqueIndex:idx_carrier_status_mappings_code,priority:1"`
+	Carrier     *ShipmentCarrier `gorm:"foreignKey:CarrierID;references:ID"`
+	Code        string           `gorm:"size:100;not null;uniqueIndex:idx_carrier_status_mappings_code,priority:2"`
+	StatusKey   *string          `gorm:"size:50"`
+	Description string           `gorm:"size:256"`
+	FirstSeenAt *time.Time
+	LastSeenAt  *time.Time
+	Occurrences int `gorm:"not null;default:0"`
+}
@@ -9,9 +9,16 @@ type Shipment struct {
 	TrackingURL         string `gorm:"size:256"`
 	DeliveryWindowStart *time.Time
 	DeliveryWindowEnd   *time.Time
-	LastLocation        string `gorm:"size:100"`
+	LastLocation        string `gorm:"type:text"`
 	LastCheckedAt       *time.Time
 	ThumbnailURL        string `gorm:"size:256"`
+	LastError           string `gorm:"type:text"`
+	LastErrorAt         *time.Time
+	ConsecutiveFailures int `gorm:"not null;default:0"`
+	NextCheckAt         *time.Time
+	// HandoffTrackingNumber is the number assigned by the carrier that took
+	// over last-mile delivery, such as USPS for DHL eCommerce.
+	HandoffTrackingNumber string `gorm:"size:100"`
 
 	// Foreign keys
 	StatusID  *uint
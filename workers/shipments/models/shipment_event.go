@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ShipmentEvent represents shipment_events table
+type ShipmentEvent struct {
+	ID                    uint      `gorm:"primaryKey;autoIncrement"`
+	ShipmentID            uint      `gorm:"not null;uniqueIndex:idx_shipment_events_identity,priority:1"`
+	OccurredAt            time.Time `gorm:"not null;uniqueIndex:idx_shipment_events_identity,priority:2"`
+	CarrierStatusCode     string    `gorm:"size:50;not null;default:'';uniqueIndex:idx_shipment_events_identity,priority:3"`
+	Location              string    `gorm:"type:text"`
+	Description           string    `gorm:"type:text"`
+	Status                string    `gorm:"size:50"`
+	OccurredOffsetSeconds *int
+}
+
+// LocalOccurredAt returns when the event happened in the time zone of the scan
+// that reported it, falling back to UTC for events recorded without an offset.
+func (e ShipmentEvent) LocalOccurredAt() time.Time {
+	if e.OccurredOffsetSeconds == nil {
+		return e.OccurredAt.UTC()
+	}
+	return e.OccurredAt.In(time.FixedZone("", *e.OccurredOffsetSeconds))
+}
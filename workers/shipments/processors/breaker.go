@@ -0,0 +1,141 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"go.uber.org/zap"
+	"personal-homepage-service/metrics"
+	"personal-homepage-service/workers/shipments/models"
+	"sync"
+	"time"
+)
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"
+	BreakerOpen     BreakerState = "open"
+	BreakerHalfOpen BreakerState = "half_open"
+)
+
+// CircuitBreaker wraps a carrier's processor and stops calling it after a run
+// of consecutive failures. Once the cooldown has passed a single probe is let
+// through; its outcome decides whether the breaker closes or reopens.
+type CircuitBreaker struct {
+	carrier   string
+	next      CarrierTrackingProcessor
+	threshold int
+	cooldown  time.Duration
+	logger    *zap.Logger
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(carrier string, next CarrierTrackingProcessor, threshold int, cooldown time.Duration, logger *zap.Logger) *CircuitBreaker {
+	metrics.SetCircuitState(carrier, string(BreakerClosed))
+	return &CircuitBreaker{
+		carrier:   carrier,
+		next:      next,
+		threshold: max(threshold, 1),
+		cooldown:  cooldown,
+		logger:    logger,
+		state:     BreakerClosed,
+	}
+}
+
+func (b *CircuitBreaker) Process(ctx context.Context, shipment models.Shipment) (*CarrierTrackingResults, error) {
+	return b.Call(func(next CarrierTrackingProcessor) (*CarrierTrackingResults, error) {
+		return next.Process(ctx, shipment)
+	})
+}
+
+// Call runs fn with the wrapped processor as a single call through the
+// breaker, so however many times fn calls the processor, such as to retry,
+// its outcome counts once.
+func (b *CircuitBreaker) Call(fn func(next CarrierTrackingProcessor) (*CarrierTrackingResults, error)) (*CarrierTrackingResults, error) {
+	if err := b.allow(); err != nil {
+		return nil, err
+	}
+
+	result, err := fn(b.next)
+	b.record(err)
+	return result, err
+}
+
+func (b *CircuitBreaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.cooldown {
+			return ErrCircuitOpen
+		}
+		b.transition(BreakerHalfOpen)
+		b.probing = true
+		return nil
+	case BreakerHalfOpen:
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record updates the breaker with the outcome of a call. Only failures that
+// point at the carrier count; a permanent error about one shipment, our own
+// cancellation, or running out of our own quota says nothing about the
+// carrier's health.
+func (b *CircuitBreaker) record(err error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, ErrRateLimited) || IsPermanent(err) {
+		b.mu.Lock()
+		b.probing = false
+		b.mu.Unlock()
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if err == nil {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.transition(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		if b.state != BreakerOpen {
+			b.transition(BreakerOpen)
+		}
+	}
+}
+
+func (b *CircuitBreaker) transition(state BreakerState) {
+	b.logger.Warn("Carrier circuit breaker state changed",
+		zap.String("carrier", b.carrier),
+		zap.String("from", string(b.state)),
+		zap.String("to", string(state)),
+		zap.Int("failures", b.failures),
+	)
+	b.state = state
+	metrics.SetCircuitState(b.carrier, string(state))
+}
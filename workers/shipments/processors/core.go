@@ -1,7 +1,10 @@
 package processors
 
-import "personal-homepage-service/workers/shipments/models"
+import (
+	"context"
+	"personal-homepage-service/workers/shipments/models"
+)
 
 type CarrierTrackingProcessor interface {
-	Process(shipment models.Shipment) (*CarrierTrackingResults, error)
+	Process(ctx context.Context, shipment models.Shipment) (*CarrierTrackingResults, error)
 }
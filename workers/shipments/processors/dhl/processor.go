@@ -0,0 +1,308 @@
+package dhl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"personal-homepage-service/config"
+	"personal-homepage-service/metrics"
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// phraseCodePrefix marks the carrier_status_mappings codes that are phrases
+// from the carrier's own wording rather than unified status codes. Phrases
+// are more specific than the code, so a matching phrase refines its status.
+const phraseCodePrefix = "phrase:"
+
+// finalStatusCodes are unified status codes that already say how the
+// shipment ended, so the wording of the event is not allowed to override them.
+var finalStatusCodes = map[string]bool{
+	"delivered": true,
+	"failure":   true,
+}
+
+// handoffReferenceType marks the reference holding the number a last-mile
+// partner, usually USPS, tracks a DHL eCommerce package under.
+const handoffReferenceType = "local-tracking-number"
+
+type TrackingProcessor struct {
+	config   *config.DhlApiConfig
+	logger   *zap.Logger
+	client   *http.Client
+	statuses *processors.StatusMapper
+}
+
+func NewTrackingProcessor(cfg *config.DhlApiConfig, logger *zap.Logger, client *http.Client, statuses *processors.StatusMapper) *TrackingProcessor {
+	return &TrackingProcessor{config: cfg, logger: logger, client: client, statuses: statuses}
+}
+
+func Register(r *processors.Registry) {
+	r.Register(processors.Carrier{
+		Key: "dhl",
+		New: func(deps processors.Dependencies) processors.CarrierTrackingProcessor {
+			return NewTrackingProcessor(deps.Config.DHLApi, deps.Logger, deps.HTTPClient, deps.Statuses)
+		},
+		Validate: validateConfig,
+	})
+}
+
+func validateConfig(cfg *config.Config) error {
+	if cfg.DHLApi == nil || cfg.DHLApi.BaseUri == "" {
+		return errors.New("DHL_API_BASE_URI is not set")
+	}
+	if cfg.DHLApi.ApiKey == "" {
+		return errors.New("DHL_API_KEY is not set")
+	}
+	return nil
+}
+
+func (p *TrackingProcessor) Process(ctx context.Context, shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
+	trackingNumber := shipment.TrackingNumber
+	details, err := p.getTrackingDetails(ctx, trackingNumber)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if len(details.Shipments) == 0 {
+		return nil, processors.PermanentError(fmt.Errorf("no shipment returned for %s", trackingNumber))
+	}
+
+	shp := details.Shipments[0]
+
+	delStart, delEnd, delErr := getExpectedDeliveryWindow(shp)
+	if delErr != nil {
+		p.logger.Error("Error parsing datetime:" + delErr.Error())
+	}
+
+	return &processors.CarrierTrackingResults{
+		TrackingNumber:        trackingNumber,
+		DeliveryWindowStart:   delStart,
+		DeliveryWindowEnd:     delEnd,
+		LastLocation:          getLastLocation(shp),
+		LastCheckedAt:         &now,
+		Status:                p.getStatusKey(shp.Status),
+		HandoffTrackingNumber: getHandoffTrackingNumber(shp, trackingNumber),
+		Events:                p.getEvents(shp.Events),
+	}, nil
+}
+
+func (p *TrackingProcessor) getEvents(statuses []Status) []processors.CarrierTrackingEvent {
+	events := make([]processors.CarrierTrackingEvent, 0, len(statuses))
+
+	for _, s := range statuses {
+		occurredAt, err := parseDatetime(s.Timestamp)
+		if err != nil {
+			p.logger.Warn("Skipping event with unparseable time",
+				zap.String("timestamp", s.Timestamp),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		description := s.Description
+		if description == "" {
+			description = s.Status
+		}
+
+		events = append(events, processors.CarrierTrackingEvent{
+			OccurredAt:        *occurredAt,
+			Location:          formatLocation(s.Location),
+			CarrierStatusCode: s.StatusCode,
+			Description:       description,
+			Status:            p.getStatusKey(s),
+		})
+	}
+
+	return events
+}
+
+// getHandoffTrackingNumber returns the number the last-mile carrier tracks the
+// package under, if DHL has handed it off.
+func getHandoffTrackingNumber(shp Shipment, trackingNumber string) string {
+	for _, ref := range shp.Details.References {
+		if ref.Type == handoffReferenceType && ref.Number != "" && ref.Number != trackingNumber {
+			return ref.Number
+		}
+	}
+	return ""
+}
+
+func getExpectedDeliveryWindow(shp Shipment) (*time.Time, *time.Time, error) {
+	frame := shp.EstimatedDeliveryTimeFrame
+	if frame.EstimatedThrough == "" {
+		if shp.EstimatedTimeOfDelivery == "" {
+			return nil, nil, nil
+		}
+		end, err := parseDatetime(shp.EstimatedTimeOfDelivery)
+		return nil, end, err
+	}
+
+	end, endErr := parseDatetime(frame.EstimatedThrough)
+	if endErr != nil || frame.EstimatedFrom == "" {
+		return nil, end, endErr
+	}
+
+	start, startErr := parseDatetime(frame.EstimatedFrom)
+	if startErr != nil {
+		return nil, end, startErr
+	}
+
+	return start, end, nil
+}
+
+// parseDatetime reads DHL timestamps. Express includes the offset of the scan
+// location; eCommerce timestamps without one are read as local time.
+func parseDatetime(value string) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", value, time.Now().Location())
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func getLastLocation(shp Shipment) string {
+	if len(shp.Events) > 0 {
+		if location := formatLocation(shp.Events[0].Location); location != "" {
+			return location
+		}
+	}
+
+	return formatLocation(shp.Status.Location)
+}
+
+// formatLocation formats a locality the way ups.formatLocation does: "City,
+// ST" inside the US and "City, CC" elsewhere. Express localities look like
+// "LEIPZIG - GERMANY" or "CINCINNATI HUB - CINCINNATI, OH - USA", so the
+// segment before the trailing country name is used.
+func formatLocation(location Location) string {
+	region := location.Address.CountryCode
+	locality := location.Address.AddressLocality
+
+	if segments := strings.Split(locality, " - "); len(segments) > 1 {
+		locality = segments[len(segments)-2]
+	}
+	locality = strings.TrimSpace(locality)
+
+	if locality == "" {
+		return region
+	}
+
+	city, state, hasState := strings.Cut(locality, ",")
+	city = processors.TitleCase(city)
+
+	if region == "US" && hasState {
+		return city + ", " + strings.ToUpper(strings.TrimSpace(state))
+	}
+
+	if region == "" {
+		return city
+	}
+
+	return city + ", " + region
+}
+
+func (p *TrackingProcessor) getStatusKey(s Status) string {
+	if !finalStatusCodes[s.StatusCode] {
+		if status, ok := p.matchPhrase(s.Status + " " + s.Description); ok {
+			return status
+		}
+	}
+
+	return p.statuses.Resolve("dhl", s.StatusCode, s.Status)
+}
+
+// matchPhrase returns the status of the longest mapped phrase text contains
+// as whole words, the way scraped carriers match status text.
+func (p *TrackingProcessor) matchPhrase(text string) (string, bool) {
+	words := splitWords(text)
+
+	best, status := "", ""
+	for code, key := range p.statuses.Mappings("dhl") {
+		phrase, ok := strings.CutPrefix(code, phraseCodePrefix)
+		if !ok || len(phrase) < len(best) || (len(phrase) == len(best) && phrase > best) {
+			continue
+		}
+		if containsPhrase(words, splitWords(phrase)) {
+			best, status = phrase, key
+		}
+	}
+
+	return status, best != ""
+}
+
+// splitWords lower cases text and splits it into words, dropping punctuation.
+func splitWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// containsPhrase reports whether words contain phrase as a run of whole
+// words that is not negated, so "no delay" does not count as a delay.
+func containsPhrase(words []string, phrase []string) bool {
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		if !slices.Equal(words[i:i+len(phrase)], phrase) {
+			continue
+		}
+		if i > 0 && (words[i-1] == "no" || words[i-1] == "not") {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (p *TrackingProcessor) getTrackingDetails(ctx context.Context, trackingNumber string) (*ApiResponse, error) {
+	u, err := url.Parse(p.config.BaseUri + "/track/shipments")
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	q.Set("trackingNumber", trackingNumber)
+	q.Set("language", "en")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(metrics.WithOperation(ctx, "track"), "GET", u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("DHL-API-Key", p.config.ApiKey)
+
+	resp, clientErr := p.client.Do(req)
+	if clientErr != nil {
+		return nil, processors.RequestError(clientErr)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, processors.StatusError(resp.StatusCode, string(bodyBytes))
+	}
+
+	var apiResponse ApiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &apiResponse, nil
+}
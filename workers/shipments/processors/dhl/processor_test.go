@@ -0,0 +1,50 @@
+package dhl
+
+import (
+	"go.uber.org/zap"
+	"personal-homepage-service/workers/shipments/processors"
+	"personal-homepage-service/workers/shipments/processors/processorstest"
+	"testing"
+)
+
+func TestGetStatusKey(t *testing.T) {
+	p := &TrackingProcessor{statuses: processors.NewStatusMapper(processorstest.NewStatusStore(map[string]map[string]string{
+		"dhl": {
+			"pre-transit": "pending",
+			"transit":     "in_transit",
+			"delivered":   "delivered",
+			"failure":     "exception",
+
+			"phrase:return to sender":   "returned",
+			"phrase:out for delivery":   "out_for_delivery",
+			"phrase:delivery attempted": "attempted_delivery",
+			"phrase:delay":              "delayed",
+			"phrase:delayed":            "delayed",
+			"phrase:picked up":          "accepted",
+		},
+	}), zap.NewNop())}
+
+	tests := []struct {
+		name   string
+		status Status
+		want   string
+	}{
+		{"code only", Status{StatusCode: "transit", Status: "Processed at facility"}, "in_transit"},
+		{"phrase refines transit", Status{StatusCode: "transit", Status: "Out for delivery"}, "out_for_delivery"},
+		{"phrase in description", Status{StatusCode: "transit", Description: "Shipment delayed by weather."}, "delayed"},
+		{"negated phrase", Status{StatusCode: "transit", Status: "In transit, no delay expected"}, "in_transit"},
+		{"partial word", Status{StatusCode: "transit", Status: "Delayable item accepted"}, "in_transit"},
+		{"delivered code wins", Status{StatusCode: "delivered", Status: "Picked up by the recipient"}, "delivered"},
+		{"failure code wins", Status{StatusCode: "failure", Status: "Return to sender after delivery attempted"}, "exception"},
+		{"phrase on pre-transit", Status{StatusCode: "pre-transit", Status: "Picked up"}, "accepted"},
+		{"longest phrase wins", Status{StatusCode: "transit", Status: "Picked up, out for delivery"}, "out_for_delivery"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.getStatusKey(tt.status); got != tt.want {
+				t.Errorf("getStatusKey(%+v) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
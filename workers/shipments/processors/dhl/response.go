@@ -0,0 +1,47 @@
+package dhl
+
+type Address struct {
+	CountryCode     string `json:"countryCode"`
+	PostalCode      string `json:"postalCode"`
+	AddressLocality string `json:"addressLocality"`
+}
+
+type Location struct {
+	Address Address `json:"address"`
+}
+
+type Status struct {
+	Timestamp   string   `json:"timestamp"`
+	Location    Location `json:"location"`
+	StatusCode  string   `json:"statusCode"`
+	Status      string   `json:"status"`
+	Description string   `json:"description"`
+}
+
+type TimeFrame struct {
+	EstimatedFrom    string `json:"estimatedFrom"`
+	EstimatedThrough string `json:"estimatedThrough"`
+}
+
+type Reference struct {
+	Number string `json:"number"`
+	Type   string `json:"type"`
+}
+
+type Details struct {
+	References []Reference `json:"references"`
+}
+
+type Shipment struct {
+	Id                         string    `json:"id"`
+	Service                    string    `json:"service"`
+	Status                     Status    `json:"status"`
+	EstimatedTimeOfDelivery    string    `json:"estimatedTimeOfDelivery"`
+	EstimatedDeliveryTimeFrame TimeFrame `json:"estimatedDeliveryTimeFrame"`
+	Details                    Details   `json:"details"`
+	Events                     []Status  `json:"events"`
+}
+
+type ApiResponse struct {
+	Shipments []Shipment `json:"shipments"`
+}
@@ -0,0 +1,122 @@
+package processors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrCircuitOpen is returned instead of calling a carrier whose circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("carrier circuit breaker is open")
+
+// ErrRateLimited is wrapped by errors for calls held back by our own rate
+// limit for a carrier, which were never sent.
+var ErrRateLimited = errors.New("carrier rate limit reached")
+
+type ErrorKind int
+
+const (
+	// ErrorKindTransient failures (timeouts, 5xx, 429) may succeed on retry.
+	ErrorKindTransient ErrorKind = iota
+	// ErrorKindPermanent failures belong to the shipment itself, such as an
+	// invalid or unknown tracking number, and will never succeed.
+	ErrorKindPermanent
+	// ErrorKindAuth failures mean our carrier credentials were rejected.
+	// Retrying will not help, but the shipment is not at fault either.
+	ErrorKindAuth
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case ErrorKindPermanent:
+		return "permanent"
+	case ErrorKindAuth:
+		return "auth"
+	default:
+		return "transient"
+	}
+}
+
+type CarrierError struct {
+	Kind       ErrorKind
+	StatusCode int
+	Err        error
+}
+
+func (e *CarrierError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("%s carrier error (status %d): %v", e.Kind, e.StatusCode, e.Err)
+	}
+	return fmt.Sprintf("%s carrier error: %v", e.Kind, e.Err)
+}
+
+func (e *CarrierError) Unwrap() error {
+	return e.Err
+}
+
+func TransientError(err error) error {
+	return &CarrierError{Kind: ErrorKindTransient, Err: err}
+}
+
+func PermanentError(err error) error {
+	return &CarrierError{Kind: ErrorKindPermanent, Err: err}
+}
+
+// StatusError classifies a non-success HTTP response from a carrier.
+func StatusError(statusCode int, body string) error {
+	err := &CarrierError{
+		StatusCode: statusCode,
+		Err:        fmt.Errorf("unexpected status %d: %s", statusCode, body),
+	}
+
+	switch {
+	case statusCode == http.StatusTooManyRequests,
+		statusCode == http.StatusRequestTimeout,
+		statusCode >= 500:
+		err.Kind = ErrorKindTransient
+	case statusCode == http.StatusUnauthorized,
+		statusCode == http.StatusForbidden:
+		err.Kind = ErrorKindAuth
+	default:
+		err.Kind = ErrorKindPermanent
+	}
+
+	return err
+}
+
+// RequestError classifies a failure to get any response from a carrier.
+// Cancellation is passed through untouched so it is never retried.
+func RequestError(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+	return TransientError(err)
+}
+
+// IsRetryable reports whether err is worth retrying. Errors a processor did
+// not classify are assumed to be transient.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+
+	var carrierErr *CarrierError
+	if errors.As(err, &carrierErr) {
+		return carrierErr.Kind == ErrorKindTransient
+	}
+	return true
+}
+
+// IsAuth reports whether err means the carrier rejected our credentials.
+func IsAuth(err error) bool {
+	var carrierErr *CarrierError
+	return errors.As(err, &carrierErr) && carrierErr.Kind == ErrorKindAuth
+}
+
+// IsPermanent reports whether err means the shipment can never be tracked.
+func IsPermanent(err error) bool {
+	var carrierErr *CarrierError
+	return errors.As(err, &carrierErr) && carrierErr.Kind == ErrorKindPermanent
+}
@@ -0,0 +1,333 @@
+package fedex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"personal-homepage-service/config"
+	"personal-homepage-service/metrics"
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"personal-homepage-service/workers/shipments/processors/oauth"
+	"strings"
+	"time"
+)
+
+// notFoundCode is returned for numbers FedEx has no scans for yet, which
+// includes labels that were only just created.
+const notFoundCode = "TRACKING.TRACKINGNUMBER.NOTFOUND"
+
+type TrackingProcessor struct {
+	config   *config.FedExApiConfig
+	logger   *zap.Logger
+	client   *http.Client
+	statuses *processors.StatusMapper
+	tokens   oauth.TokenCache
+}
+
+func NewTrackingProcessor(cfg *config.FedExApiConfig, logger *zap.Logger, client *http.Client, statuses *processors.StatusMapper) *TrackingProcessor {
+	return &TrackingProcessor{config: cfg, logger: logger, client: client, statuses: statuses}
+}
+
+func Register(r *processors.Registry) {
+	r.Register(processors.Carrier{
+		Key: "fedex",
+		New: func(deps processors.Dependencies) processors.CarrierTrackingProcessor {
+			return NewTrackingProcessor(deps.Config.FedExApi, deps.Logger, deps.HTTPClient, deps.Statuses)
+		},
+		Validate: validateConfig,
+	})
+}
+
+func validateConfig(cfg *config.Config) error {
+	if cfg.FedExApi == nil || cfg.FedExApi.BaseUri == "" {
+		return errors.New("FEDEX_API_BASE_URI is not set")
+	}
+	if cfg.FedExApi.ClientId == "" || cfg.FedExApi.ClientSecret == "" {
+		return errors.New("FEDEX_API_CLIENT_ID and FEDEX_API_CLIENT_SECRET must be set")
+	}
+	return nil
+}
+
+func (p *TrackingProcessor) Process(ctx context.Context, shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
+	trackingNumber := shipment.TrackingNumber
+	details, err := p.getTrackingDetails(ctx, trackingNumber)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if len(details.Output.CompleteTrackResults) == 0 || len(details.Output.CompleteTrackResults[0].TrackResults) == 0 {
+		return nil, processors.PermanentError(fmt.Errorf("no shipment returned for %s", trackingNumber))
+	}
+
+	result := details.Output.CompleteTrackResults[0].TrackResults[0]
+
+	if result.Error != nil {
+		if result.Error.Code == notFoundCode {
+			return &processors.CarrierTrackingResults{
+				TrackingNumber: trackingNumber,
+				LastCheckedAt:  &now,
+				Status:         "pending",
+			}, nil
+		}
+		return nil, processors.PermanentError(fmt.Errorf("%s: %s", result.Error.Code, result.Error.Message))
+	}
+
+	latest := result.LatestStatusDetail
+	status := p.getStatusKey(latest.Code, latest.Description)
+	if status == "unknown" && latest.DerivedCode != latest.Code {
+		status = p.getStatusKey(latest.DerivedCode, latest.StatusByLocale)
+	}
+
+	delStart, delEnd, delErr := getExpectedDeliveryWindow(result)
+	if delErr != nil {
+		p.logger.Error("Error parsing datetime:" + delErr.Error())
+	}
+
+	return &processors.CarrierTrackingResults{
+		TrackingNumber:      trackingNumber,
+		DeliveryWindowStart: delStart,
+		DeliveryWindowEnd:   delEnd,
+		LastLocation:        getLastLocation(result),
+		LastCheckedAt:       &now,
+		Status:              status,
+		Events:              p.getEvents(result.ScanEvents),
+	}, nil
+}
+
+func (p *TrackingProcessor) getEvents(scans []ScanEvent) []processors.CarrierTrackingEvent {
+	events := make([]processors.CarrierTrackingEvent, 0, len(scans))
+
+	for _, s := range scans {
+		occurredAt, err := parseDatetime(s.Date)
+		if err != nil {
+			p.logger.Warn("Skipping scan event with unparseable time",
+				zap.String("date", s.Date),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		code := s.EventType
+		if code == "" {
+			code = s.DerivedStatusCode
+		}
+
+		events = append(events, processors.CarrierTrackingEvent{
+			OccurredAt:        *occurredAt,
+			Location:          formatLocation(s.ScanLocation),
+			CarrierStatusCode: code,
+			Description:       s.EventDescription,
+			Status:            p.getStatusKey(code, s.EventDescription),
+		})
+	}
+
+	return events
+}
+
+// getExpectedDeliveryWindow prefers the estimated delivery window, then the
+// estimated delivery date, then the standard transit window. Delivered
+// shipments report when they were delivered instead.
+func getExpectedDeliveryWindow(r TrackResult) (*time.Time, *time.Time, error) {
+	if actual := findDateAndTime(r.DateAndTimes, "ACTUAL_DELIVERY"); actual != "" {
+		t, err := parseDatetime(actual)
+		return t, t, err
+	}
+
+	window := r.EstimatedDeliveryTimeWindow.Window
+	if window.Ends == "" && window.Begins == "" {
+		if estimated := findDateAndTime(r.DateAndTimes, "ESTIMATED_DELIVERY"); estimated != "" {
+			window = Window{Ends: estimated}
+		} else {
+			window = r.StandardTransitTimeWindow.Window
+		}
+	}
+
+	if window.Ends == "" {
+		return nil, nil, nil
+	}
+
+	end, endErr := parseDatetime(window.Ends)
+	if endErr != nil || window.Begins == "" {
+		return nil, end, endErr
+	}
+
+	start, startErr := parseDatetime(window.Begins)
+	if startErr != nil {
+		return nil, end, startErr
+	}
+
+	return start, end, nil
+}
+
+func findDateAndTime(dates []DateAndTime, dateType string) string {
+	for _, d := range dates {
+		if d.Type == dateType {
+			return d.DateTime
+		}
+	}
+	return ""
+}
+
+// parseDatetime reads FedEx timestamps, which carry the offset of the scan
+// location. Timestamps without one are read as local time.
+func parseDatetime(value string) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.ParseInLocation("2006-01-02T15:04:05", value, time.Now().Location())
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func getLastLocation(r TrackResult) string {
+	if len(r.ScanEvents) > 0 {
+		if location := formatLocation(r.ScanEvents[0].ScanLocation); location != "" {
+			return location
+		}
+	}
+
+	return formatLocation(r.LatestStatusDetail.ScanLocation)
+}
+
+func formatLocation(location Location) string {
+	region := location.CountryCode
+
+	if location.City == "" {
+		return region
+	}
+
+	if location.CountryCode == "US" {
+		region = location.StateOrProvinceCode
+	}
+
+	return processors.TitleCase(location.City) + ", " + region
+}
+
+func (p *TrackingProcessor) getStatusKey(code string, description string) string {
+	return p.statuses.Resolve("fedex", code, description)
+}
+
+func (p *TrackingProcessor) getAccessToken(ctx context.Context) (string, error) {
+	return p.tokens.Get(ctx, p.requestAccessToken)
+}
+
+func (p *TrackingProcessor) requestAccessToken(ctx context.Context) (token *oauth.Token, err error) {
+	defer func() {
+		metrics.ObserveTokenRefresh("fedex", err)
+	}()
+
+	data := url.Values{}
+	data.Set("grant_type", "client_credentials")
+	data.Set("client_id", p.config.ClientId)
+	data.Set("client_secret", p.config.ClientSecret)
+
+	req, err := http.NewRequestWithContext(metrics.WithOperation(ctx, "oauth"), "POST", p.config.BaseUri+"/oauth/token", strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	httpResp, err := p.client.Do(req)
+	if err != nil {
+		return nil, processors.RequestError(err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(httpResp.Body)
+
+	if httpResp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(httpResp.Body)
+		return nil, processors.StatusError(httpResp.StatusCode, string(bodyBytes))
+	}
+
+	var authResponse OAuthResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&authResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &oauth.Token{
+		AccessToken: authResponse.AccessToken,
+		ExpiresIn:   oauth.Seconds(authResponse.ExpiresIn),
+	}, nil
+}
+
+func (p *TrackingProcessor) getTrackingDetails(ctx context.Context, trackingNumber string) (*ApiResponse, error) {
+	body, err := json.Marshal(TrackRequest{
+		IncludeDetailedScans: true,
+		TrackingInfo: []TrackingInfo{
+			{TrackingNumberInfo: TrackingNumberInfo{TrackingNumber: trackingNumber}},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := p.getAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	details, status, err := p.requestTrackingDetails(ctx, body, accessToken)
+
+	// A 401 means the cached token was revoked or expired early; refresh it
+	// once and retry.
+	if status == http.StatusUnauthorized {
+		p.tokens.Invalidate(accessToken)
+
+		accessToken, err = p.getAccessToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		details, _, err = p.requestTrackingDetails(ctx, body, accessToken)
+	}
+
+	return details, err
+}
+
+func (p *TrackingProcessor) requestTrackingDetails(ctx context.Context, body []byte, accessToken string) (*ApiResponse, int, error) {
+	endpoint := p.config.BaseUri + "/track/v1/trackingnumbers"
+
+	req, err := http.NewRequestWithContext(metrics.WithOperation(ctx, "track"), "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("X-locale", "en_US")
+	req.Header.Set("x-customer-transaction-id", uuid.New().String())
+
+	resp, clientErr := p.client.Do(req)
+	if clientErr != nil {
+		return nil, 0, processors.RequestError(clientErr)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, resp.StatusCode, processors.StatusError(resp.StatusCode, string(bodyBytes))
+	}
+
+	var apiResponse ApiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &apiResponse, resp.StatusCode, nil
+}
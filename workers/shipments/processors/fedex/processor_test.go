@@ -0,0 +1,313 @@
+package fedex
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"personal-homepage-service/config"
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"personal-homepage-service/workers/shipments/processors/processorstest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFedEx serves the OAuth and Track endpoints, handing out numbered tokens
+// and answering track requests with response.
+type fakeFedEx struct {
+	t        *testing.T
+	mu       sync.Mutex
+	tokens   int
+	tracks   int
+	rejected map[string]bool
+	response ApiResponse
+}
+
+func (f *fakeFedEx) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/oauth/token":
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("client_id") != "client-id" {
+			f.t.Errorf("unexpected token request form: %v", r.PostForm)
+		}
+		f.tokens++
+		_ = json.NewEncoder(w).Encode(OAuthResponse{
+			AccessToken: fmt.Sprintf("token-%d", f.tokens),
+			ExpiresIn:   3600,
+		})
+	case "/track/v1/trackingnumbers":
+		f.tracks++
+		token := r.Header.Get("Authorization")[len("Bearer "):]
+		if f.rejected[token] {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(f.response)
+	default:
+		f.t.Errorf("unexpected request to %s", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeFedEx) counts() (tokens int, tracks int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tokens, f.tracks
+}
+
+func (f *fakeFedEx) reject(token string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rejected[token] = true
+}
+
+func newTestProcessor(t *testing.T, fake *fakeFedEx) *TrackingProcessor {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	cfg := &config.FedExApiConfig{BaseUri: srv.URL, ClientId: "client-id", ClientSecret: "client-secret"}
+	statuses := processors.NewStatusMapper(processorstest.NewStatusStore(map[string]map[string]string{
+		"fedex": {
+			"DL": "delivered",
+			"IT": "in_transit",
+		},
+	}), zap.NewNop())
+	return NewTrackingProcessor(cfg, zap.NewNop(), srv.Client(), statuses)
+}
+
+func trackResponse(result TrackResult) ApiResponse {
+	return ApiResponse{Output: TrackOutput{CompleteTrackResults: []CompleteTrackResult{
+		{TrackingNumber: "794843185271", TrackResults: []TrackResult{result}},
+	}}}
+}
+
+var shipment = models.Shipment{TrackingNumber: "794843185271"}
+
+func TestProcessReusesTokenAndRefreshesOnceOnUnauthorized(t *testing.T) {
+	fake := &fakeFedEx{
+		t:        t,
+		rejected: map[string]bool{},
+		response: trackResponse(TrackResult{
+			LatestStatusDetail: StatusDetail{Code: "IT", Description: "In transit"},
+		}),
+	}
+	p := newTestProcessor(t, fake)
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := p.Process(ctx, shipment); err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+	}
+	if tokens, tracks := fake.counts(); tokens != 1 || tracks != 2 {
+		t.Fatalf("got %d token and %d track requests, want 1 and 2", tokens, tracks)
+	}
+
+	// The cached token is revoked: one refresh, then the retry succeeds.
+	fake.reject("token-1")
+	result, err := p.Process(ctx, shipment)
+	if err != nil {
+		t.Fatalf("Process() after revocation error = %v", err)
+	}
+	if result.Status != "in_transit" {
+		t.Errorf("Status = %q, want in_transit", result.Status)
+	}
+	if tokens, tracks := fake.counts(); tokens != 2 || tracks != 4 {
+		t.Fatalf("got %d token and %d track requests, want 2 and 4", tokens, tracks)
+	}
+
+	// A fresh token that is rejected as well is not refreshed again.
+	fake.reject("token-2")
+	fake.reject("token-3")
+	_, err = p.Process(ctx, shipment)
+	if !processors.IsAuth(err) {
+		t.Fatalf("Process() with rejected credentials error = %v, want an auth error", err)
+	}
+	if tokens, tracks := fake.counts(); tokens != 3 || tracks != 6 {
+		t.Errorf("got %d token and %d track requests, want 3 and 6", tokens, tracks)
+	}
+}
+
+func TestProcessNotFoundIsPending(t *testing.T) {
+	fake := &fakeFedEx{
+		t: t,
+		response: trackResponse(TrackResult{
+			Error: &Error{Code: notFoundCode, Message: "Tracking number cannot be found."},
+		}),
+	}
+
+	result, err := newTestProcessor(t, fake).Process(context.Background(), shipment)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if result.Status != "pending" {
+		t.Errorf("Status = %q, want pending", result.Status)
+	}
+}
+
+func TestProcessOtherErrorIsPermanent(t *testing.T) {
+	fake := &fakeFedEx{
+		t: t,
+		response: trackResponse(TrackResult{
+			Error: &Error{Code: "TRACKING.TRACKINGNUMBER.INVALID", Message: "Invalid tracking number."},
+		}),
+	}
+
+	_, err := newTestProcessor(t, fake).Process(context.Background(), shipment)
+	if !processors.IsPermanent(err) {
+		t.Errorf("Process() error = %v, want a permanent error", err)
+	}
+}
+
+func TestGetExpectedDeliveryWindow(t *testing.T) {
+	const (
+		actual    = "2024-03-04T14:32:00-05:00"
+		begins    = "2024-03-05T09:00:00-05:00"
+		ends      = "2024-03-05T17:00:00-05:00"
+		estimated = "2024-03-06T20:00:00-05:00"
+		stdBegins = "2024-03-07T08:00:00-05:00"
+		stdEnds   = "2024-03-08T20:00:00-05:00"
+	)
+
+	estimatedWindow := TimeWindow{Window: Window{Begins: begins, Ends: ends}}
+	standardWindow := TimeWindow{Window: Window{Begins: stdBegins, Ends: stdEnds}}
+	actualDate := DateAndTime{Type: "ACTUAL_DELIVERY", DateTime: actual}
+	estimatedDate := DateAndTime{Type: "ESTIMATED_DELIVERY", DateTime: estimated}
+
+	tests := []struct {
+		name      string
+		result    TrackResult
+		wantStart string
+		wantEnd   string
+	}{
+		{
+			name: "actual delivery wins",
+			result: TrackResult{
+				DateAndTimes:                []DateAndTime{estimatedDate, actualDate},
+				EstimatedDeliveryTimeWindow: estimatedWindow,
+				StandardTransitTimeWindow:   standardWindow,
+			},
+			wantStart: actual,
+			wantEnd:   actual,
+		},
+		{
+			name: "estimated window",
+			result: TrackResult{
+				DateAndTimes:                []DateAndTime{estimatedDate},
+				EstimatedDeliveryTimeWindow: estimatedWindow,
+				StandardTransitTimeWindow:   standardWindow,
+			},
+			wantStart: begins,
+			wantEnd:   ends,
+		},
+		{
+			name: "estimated delivery date",
+			result: TrackResult{
+				DateAndTimes:              []DateAndTime{estimatedDate},
+				StandardTransitTimeWindow: standardWindow,
+			},
+			wantEnd: estimated,
+		},
+		{
+			name:      "standard transit window",
+			result:    TrackResult{StandardTransitTimeWindow: standardWindow},
+			wantStart: stdBegins,
+			wantEnd:   stdEnds,
+		},
+		{
+			name:   "nothing known",
+			result: TrackResult{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := getExpectedDeliveryWindow(tt.result)
+			if err != nil {
+				t.Fatalf("getExpectedDeliveryWindow() error = %v", err)
+			}
+			assertTime(t, "start", start, tt.wantStart)
+			assertTime(t, "end", end, tt.wantEnd)
+		})
+	}
+}
+
+func assertTime(t *testing.T, name string, got *time.Time, want string) {
+	t.Helper()
+
+	if want == "" {
+		if got != nil {
+			t.Errorf("%s = %s, want nil", name, got)
+		}
+		return
+	}
+
+	if got == nil || got.Format(time.RFC3339) != want {
+		t.Errorf("%s = %v, want %s", name, got, want)
+	}
+}
+
+func TestGetLastLocation(t *testing.T) {
+	memphis := Location{City: "MEMPHIS", StateOrProvinceCode: "TN", CountryCode: "US"}
+	mississauga := Location{City: "MISSISSAUGA", StateOrProvinceCode: "ON", CountryCode: "CA"}
+
+	tests := []struct {
+		name   string
+		result TrackResult
+		want   string
+	}{
+		{
+			name: "latest scan event",
+			result: TrackResult{
+				ScanEvents:         []ScanEvent{{ScanLocation: memphis}, {ScanLocation: mississauga}},
+				LatestStatusDetail: StatusDetail{ScanLocation: mississauga},
+			},
+			want: "Memphis, TN",
+		},
+		{
+			name: "scan event without a location",
+			result: TrackResult{
+				ScanEvents:         []ScanEvent{{}},
+				LatestStatusDetail: StatusDetail{ScanLocation: mississauga},
+			},
+			want: "Mississauga, CA",
+		},
+		{
+			name:   "no scan events",
+			result: TrackResult{LatestStatusDetail: StatusDetail{ScanLocation: memphis}},
+			want:   "Memphis, TN",
+		},
+		{
+			name:   "country only",
+			result: TrackResult{LatestStatusDetail: StatusDetail{ScanLocation: Location{CountryCode: "US"}}},
+			want:   "US",
+		},
+		{
+			name: "multi-word city",
+			result: TrackResult{LatestStatusDetail: StatusDetail{
+				ScanLocation: Location{City: "SALT LAKE CITY", StateOrProvinceCode: "UT", CountryCode: "US"},
+			}},
+			want: "Salt Lake City, UT",
+		},
+		{
+			name:   "nothing known",
+			result: TrackResult{},
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getLastLocation(tt.result); got != tt.want {
+				t.Errorf("getLastLocation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
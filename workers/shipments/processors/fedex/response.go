@@ -0,0 +1,89 @@
+package fedex
+
+type OAuthResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+type TrackRequest struct {
+	IncludeDetailedScans bool           `json:"includeDetailedScans"`
+	TrackingInfo         []TrackingInfo `json:"trackingInfo"`
+}
+
+type TrackingInfo struct {
+	TrackingNumberInfo TrackingNumberInfo `json:"trackingNumberInfo"`
+}
+
+type TrackingNumberInfo struct {
+	TrackingNumber string `json:"trackingNumber"`
+}
+
+type Location struct {
+	City                string `json:"city"`
+	StateOrProvinceCode string `json:"stateOrProvinceCode"`
+	PostalCode          string `json:"postalCode"`
+	CountryCode         string `json:"countryCode"`
+}
+
+type StatusDetail struct {
+	Code           string   `json:"code"`
+	DerivedCode    string   `json:"derivedCode"`
+	StatusByLocale string   `json:"statusByLocale"`
+	Description    string   `json:"description"`
+	ScanLocation   Location `json:"scanLocation"`
+}
+
+type DateAndTime struct {
+	Type     string `json:"type"`
+	DateTime string `json:"dateTime"`
+}
+
+type Window struct {
+	Begins string `json:"begins"`
+	Ends   string `json:"ends"`
+}
+
+type TimeWindow struct {
+	Type   string `json:"type"`
+	Window Window `json:"window"`
+}
+
+type ScanEvent struct {
+	Date              string   `json:"date"`
+	EventType         string   `json:"eventType"`
+	EventDescription  string   `json:"eventDescription"`
+	DerivedStatusCode string   `json:"derivedStatusCode"`
+	DerivedStatus     string   `json:"derivedStatus"`
+	ScanLocation      Location `json:"scanLocation"`
+}
+
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type TrackResult struct {
+	TrackingNumberInfo          TrackingNumberInfo `json:"trackingNumberInfo"`
+	LatestStatusDetail          StatusDetail       `json:"latestStatusDetail"`
+	DateAndTimes                []DateAndTime      `json:"dateAndTimes"`
+	EstimatedDeliveryTimeWindow TimeWindow         `json:"estimatedDeliveryTimeWindow"`
+	StandardTransitTimeWindow   TimeWindow         `json:"standardTransitTimeWindow"`
+	ScanEvents                  []ScanEvent        `json:"scanEvents"`
+	Error                       *Error             `json:"error"`
+}
+
+type CompleteTrackResult struct {
+	TrackingNumber string        `json:"trackingNumber"`
+	TrackResults   []TrackResult `json:"trackResults"`
+}
+
+type TrackOutput struct {
+	CompleteTrackResults []CompleteTrackResult `json:"completeTrackResults"`
+}
+
+type ApiResponse struct {
+	TransactionId string      `json:"transactionId"`
+	Output        TrackOutput `json:"output"`
+}
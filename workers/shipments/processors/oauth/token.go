@@ -0,0 +1,66 @@
+package oauth
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// refreshMargin is how long before expiry a cached token is replaced, so a
+// token never expires between being handed out and being used.
+const refreshMargin = time.Minute
+
+// Token is an access token returned by a carrier's client credentials grant.
+// A zero ExpiresIn means the token may only be used once.
+type Token struct {
+	AccessToken string
+	ExpiresIn   time.Duration
+}
+
+// TokenCache holds the current OAuth access token for one carrier. Refreshes
+// are serialized by mu, so concurrent callers that find the token stale wait
+// for a single refresh instead of each requesting their own.
+type TokenCache struct {
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (c *TokenCache) Get(ctx context.Context, refresh func(ctx context.Context) (*Token, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && time.Now().Before(c.expiresAt.Add(-refreshMargin)) {
+		return c.token, nil
+	}
+
+	resp, err := refresh(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = resp.AccessToken
+	c.expiresAt = time.Now().Add(resp.ExpiresIn)
+	return c.token, nil
+}
+
+// Invalidate drops token if it is still the cached one. Callers that were
+// rejected with an already replaced token leave the newer token alone.
+func (c *TokenCache) Invalidate(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token == token {
+		c.token = ""
+		c.expiresAt = time.Time{}
+	}
+}
+
+// Seconds converts an expires_in value in seconds to a duration. Anything not
+// positive is treated as already expired.
+func Seconds(seconds int) time.Duration {
+	if seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,74 @@
+// Package processorstest provides fakes for testing carrier processors.
+package processorstest
+
+import "sync"
+
+// UnmappedStatus is a call to StatusStore.RecordUnmappedStatus.
+type UnmappedStatus struct {
+	Carrier     string
+	Code        string
+	Description string
+	Seen        int
+}
+
+// StatusStore is an in-memory processors.StatusStore.
+type StatusStore struct {
+	mu       sync.Mutex
+	mappings map[string]map[string]string
+	block    map[string]chan struct{}
+	err      error
+	recorded []UnmappedStatus
+}
+
+// NewStatusStore returns a store holding mappings, keyed by carrier and then
+// by code.
+func NewStatusStore(mappings map[string]map[string]string) *StatusStore {
+	return &StatusStore{mappings: mappings, block: make(map[string]chan struct{})}
+}
+
+// Block makes loading the carrier's mappings wait until the returned function
+// is called.
+func (s *StatusStore) Block(carrier string) (release func()) {
+	ch := make(chan struct{})
+	s.mu.Lock()
+	s.block[carrier] = ch
+	s.mu.Unlock()
+	return func() { close(ch) }
+}
+
+// FailRecording makes RecordUnmappedStatus return err; nil restores it.
+func (s *StatusStore) FailRecording(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err
+}
+
+// Recorded returns every unmapped status recorded so far.
+func (s *StatusStore) Recorded() []UnmappedStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]UnmappedStatus(nil), s.recorded...)
+}
+
+func (s *StatusStore) GetStatusMappings(carrier string) (map[string]string, error) {
+	s.mu.Lock()
+	block := s.block[carrier]
+	mappings := s.mappings[carrier]
+	s.mu.Unlock()
+
+	if block != nil {
+		<-block
+	}
+	return mappings, nil
+}
+
+func (s *StatusStore) RecordUnmappedStatus(carrier string, code string, description string, seen int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+	s.recorded = append(s.recorded, UnmappedStatus{carrier, code, description, seen})
+	return nil
+}
@@ -0,0 +1,119 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"personal-homepage-service/config"
+	"personal-homepage-service/metrics"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrCarrierNotConfigured = errors.New("carrier is not configured")
+
+// Dependencies are injected into every carrier processor factory.
+type Dependencies struct {
+	Config     *config.Config
+	Logger     *zap.Logger
+	HTTPClient *http.Client
+	Statuses   *StatusMapper
+}
+
+type Factory func(deps Dependencies) CarrierTrackingProcessor
+
+// Carrier describes how to build the processor for one ShipmentCarrier.Key.
+// Validate reports missing or invalid configuration and may be nil for
+// carriers that need none.
+type Carrier struct {
+	Key      string
+	New      Factory
+	Validate func(cfg *config.Config) error
+}
+
+type CarrierReport struct {
+	Key        string
+	Configured bool
+	Error      string
+}
+
+// Registry resolves carrier keys to processors. Keys without a registration
+// resolve to the fallback.
+type Registry struct {
+	mu       sync.RWMutex
+	carriers map[string]Carrier
+	fallback Factory
+	statuses *StatusMapper
+}
+
+func NewRegistry(fallback Factory, statuses *StatusMapper) *Registry {
+	return &Registry{
+		carriers: make(map[string]Carrier),
+		fallback: fallback,
+		statuses: statuses,
+	}
+}
+
+func (r *Registry) Register(carrier Carrier) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.carriers[carrier.Key] = carrier
+}
+
+// New builds the processor for key, or returns ErrCarrierNotConfigured if the
+// carrier is registered but its configuration is invalid.
+func (r *Registry) New(key string, cfg *config.Config, logger *zap.Logger) (CarrierTrackingProcessor, error) {
+	r.mu.RLock()
+	carrier, ok := r.carriers[key]
+	r.mu.RUnlock()
+
+	deps := Dependencies{
+		Config:     cfg,
+		Logger:     logger.With(zap.String("carrier", key)),
+		HTTPClient: newHTTPClient(key),
+		Statuses:   r.statuses,
+	}
+
+	if !ok {
+		return r.fallback(deps), nil
+	}
+
+	if carrier.Validate != nil {
+		if err := carrier.Validate(cfg); err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", ErrCarrierNotConfigured, key, err)
+		}
+	}
+
+	return carrier.New(deps), nil
+}
+
+// Report lists every registered carrier and whether its configuration is
+// valid, sorted by key.
+func (r *Registry) Report(cfg *config.Config) []CarrierReport {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ret := make([]CarrierReport, 0, len(r.carriers))
+	for key, carrier := range r.carriers {
+		report := CarrierReport{Key: key, Configured: true}
+		if carrier.Validate != nil {
+			if err := carrier.Validate(cfg); err != nil {
+				report.Configured = false
+				report.Error = err.Error()
+			}
+		}
+		ret = append(ret, report)
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Key < ret[j].Key })
+	return ret
+}
+
+func newHTTPClient(carrier string) *http.Client {
+	return &http.Client{
+		Timeout:   20 * time.Second,
+		Transport: metrics.CarrierTransport(carrier, nil),
+	}
+}
@@ -0,0 +1,252 @@
+package scrape
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gocolly/colly/v2"
+	"go.uber.org/zap"
+	"net/http"
+	"net/url"
+	"personal-homepage-service/config"
+	"personal-homepage-service/metrics"
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const trackingNumberPlaceholder = "{tracking_number}"
+
+// TrackingProcessor scrapes a carrier's tracking page as described by a
+// CarrierScrapeDefinition, so small carriers can be added without code.
+type TrackingProcessor struct {
+	def             models.CarrierScrapeDefinition
+	logger          *zap.Logger
+	client          *http.Client
+	locationPattern *regexp.Regexp
+	etaPattern      *regexp.Regexp
+	phrases         []string
+}
+
+func NewTrackingProcessor(def models.CarrierScrapeDefinition, logger *zap.Logger, client *http.Client) (*TrackingProcessor, error) {
+	p := &TrackingProcessor{def: def, logger: logger, client: client}
+
+	var err error
+	if p.locationPattern, err = compile(def.LocationPattern); err != nil {
+		return nil, fmt.Errorf("invalid location pattern: %w", err)
+	}
+	if p.etaPattern, err = compile(def.ETAPattern); err != nil {
+		return nil, fmt.Errorf("invalid ETA pattern: %w", err)
+	}
+
+	// Longest phrases first, so "out for delivery" wins over "delivery".
+	for phrase := range def.StatusMap {
+		p.phrases = append(p.phrases, phrase)
+	}
+	sort.Slice(p.phrases, func(i, j int) bool {
+		if len(p.phrases[i]) != len(p.phrases[j]) {
+			return len(p.phrases[i]) > len(p.phrases[j])
+		}
+		return p.phrases[i] < p.phrases[j]
+	})
+
+	return p, nil
+}
+
+// Register adds a carrier for every definition. Carriers registered
+// afterwards under the same key, such as those with a dedicated processor,
+// replace them.
+func Register(r *processors.Registry, definitions []models.CarrierScrapeDefinition) {
+	for _, def := range definitions {
+		if def.Carrier == nil {
+			continue
+		}
+
+		r.Register(processors.Carrier{
+			Key: def.Carrier.Key,
+			New: func(deps processors.Dependencies) processors.CarrierTrackingProcessor {
+				// Validate has already compiled the patterns successfully.
+				p, _ := NewTrackingProcessor(def, deps.Logger, deps.HTTPClient)
+				return p
+			},
+			Validate: func(*config.Config) error {
+				return validateDefinition(def)
+			},
+		})
+	}
+}
+
+func validateDefinition(def models.CarrierScrapeDefinition) error {
+	if def.URLTemplate == "" {
+		return errors.New("scrape definition has no URL template")
+	}
+	if def.StatusSelector == "" {
+		return errors.New("scrape definition has no status selector")
+	}
+	if def.ETASelector != "" && len(def.DateLayouts) == 0 {
+		return errors.New("scrape definition has an ETA selector but no date layouts")
+	}
+	_, err := NewTrackingProcessor(def, nil, nil)
+	return err
+}
+
+func (p *TrackingProcessor) Process(ctx context.Context, shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
+	trackingNumber := shipment.TrackingNumber
+	now := time.Now()
+
+	var statusText, location string
+	var expected *time.Time
+
+	c := colly.NewCollector(colly.StdlibContext(metrics.WithOperation(ctx, "track")))
+	c.WithTransport(p.client.Transport)
+	c.SetRequestTimeout(p.client.Timeout)
+
+	c.OnHTML(p.def.StatusSelector, func(e *colly.HTMLElement) {
+		if statusText == "" {
+			statusText = normalizeText(e.Text)
+		}
+	})
+
+	if p.def.LocationSelector != "" {
+		c.OnHTML(p.def.LocationSelector, func(e *colly.HTMLElement) {
+			if location == "" {
+				location = extract(p.locationPattern, normalizeText(e.Text))
+			}
+		})
+	}
+
+	if p.def.ETASelector != "" {
+		c.OnHTML(p.def.ETASelector, func(e *colly.HTMLElement) {
+			if expected != nil {
+				return
+			}
+
+			text := extract(p.etaPattern, normalizeText(e.Text))
+			if text == "" {
+				return
+			}
+
+			t, err := p.parseDate(text, now)
+			if err != nil {
+				p.logger.Warn("Failed to parse ETA",
+					zap.String("text", text),
+					zap.Error(err),
+				)
+				return
+			}
+			expected = t
+		})
+	}
+
+	statusCode := 0
+	c.OnError(func(r *colly.Response, _ error) {
+		statusCode = r.StatusCode
+	})
+
+	if err := c.Visit(p.trackingURL(trackingNumber)); err != nil {
+		if statusCode != 0 {
+			return nil, processors.StatusError(statusCode, err.Error())
+		}
+		return nil, processors.RequestError(err)
+	}
+
+	if statusText == "" {
+		return nil, processors.TransientError(fmt.Errorf("no element matched status selector %q", p.def.StatusSelector))
+	}
+
+	if location == "" {
+		location = shipment.LastLocation
+	}
+
+	return &processors.CarrierTrackingResults{
+		TrackingNumber:    trackingNumber,
+		DeliveryWindowEnd: expected,
+		LastLocation:      location,
+		LastCheckedAt:     &now,
+		Status:            p.getStatusKey(statusText),
+	}, nil
+}
+
+func (p *TrackingProcessor) trackingURL(trackingNumber string) string {
+	return strings.ReplaceAll(p.def.URLTemplate, trackingNumberPlaceholder, url.QueryEscape(trackingNumber))
+}
+
+// getStatusKey matches the status text exactly first, then by the longest
+// phrase it contains.
+func (p *TrackingProcessor) getStatusKey(text string) string {
+	text = strings.ToLower(text)
+
+	for phrase, status := range p.def.StatusMap {
+		if strings.ToLower(phrase) == text {
+			return status
+		}
+	}
+
+	for _, phrase := range p.phrases {
+		if strings.Contains(text, strings.ToLower(phrase)) {
+			return p.def.StatusMap[phrase]
+		}
+	}
+
+	return "unknown"
+}
+
+// parseDate tries each layout in turn. Layouts without a year, such as
+// "Mon Jan 2", are placed in the year that puts the date nearest to now.
+func (p *TrackingProcessor) parseDate(text string, now time.Time) (*time.Time, error) {
+	loc := now.Location()
+
+	for _, layout := range p.def.DateLayouts {
+		t, err := time.ParseInLocation(layout, text, loc)
+		if err != nil {
+			continue
+		}
+
+		if t.Year() == 0 {
+			t = t.AddDate(now.Year(), 0, 0)
+			if now.Sub(t) > 180*24*time.Hour {
+				t = t.AddDate(1, 0, 0)
+			} else if t.Sub(now) > 180*24*time.Hour {
+				t = t.AddDate(-1, 0, 0)
+			}
+		}
+
+		return &t, nil
+	}
+
+	return nil, fmt.Errorf("no layout matched %q", text)
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// extract returns the first capture group of pattern in text, or the whole
+// text when there is no pattern. It returns "" when the pattern does not
+// match.
+func extract(pattern *regexp.Regexp, text string) string {
+	if pattern == nil {
+		return text
+	}
+
+	matches := pattern.FindStringSubmatch(text)
+	switch len(matches) {
+	case 0:
+		return ""
+	case 1:
+		return strings.TrimSpace(matches[0])
+	default:
+		return strings.TrimSpace(matches[1])
+	}
+}
+
+// normalizeText collapses whitespace, including &nbsp;, to single spaces.
+func normalizeText(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
@@ -0,0 +1,204 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"testing"
+	"time"
+)
+
+const trackingPage = `<!doctype html>
+<html><body>
+  <div class="summary">
+    <span class="status">  Out&nbsp;for
+      delivery </span>
+    <p class="eta">Expected by Mar 5</p>
+  </div>
+  <ul>
+    <li class="event"><span class="location">Arrived at ATLANTA, GA hub</span></li>
+    <li class="event"><span class="location">Departed MIAMI, FL hub</span></li>
+  </ul>
+</body></html>`
+
+func newTestDefinition(baseURL string) models.CarrierScrapeDefinition {
+	return models.CarrierScrapeDefinition{
+		Carrier:          &models.ShipmentCarrier{Key: "lasership"},
+		URLTemplate:      baseURL + "/track?number={tracking_number}",
+		StatusSelector:   ".status",
+		LocationSelector: ".event .location",
+		LocationPattern:  `at (.+) hub`,
+		ETASelector:      ".eta",
+		ETAPattern:       `by (\w+ \d+)`,
+		DateLayouts:      []string{"Jan 2"},
+		StatusMap: map[string]string{
+			"delivery":         "in_transit",
+			"out for delivery": "out_for_delivery",
+			"delivered":        "delivered",
+		},
+	}
+}
+
+func newTestProcessor(t *testing.T, handler http.HandlerFunc) *TrackingProcessor {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	def := newTestDefinition(srv.URL)
+	if err := validateDefinition(def); err != nil {
+		t.Fatalf("validateDefinition() error = %v", err)
+	}
+
+	p, err := NewTrackingProcessor(def, zap.NewNop(), srv.Client())
+	if err != nil {
+		t.Fatalf("NewTrackingProcessor() error = %v", err)
+	}
+	return p
+}
+
+func TestProcess(t *testing.T) {
+	var requested string
+	p := newTestProcessor(t, func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Query().Get("number")
+		_, _ = fmt.Fprint(w, trackingPage)
+	})
+
+	result, err := p.Process(context.Background(), models.Shipment{TrackingNumber: "1LS 7311"})
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	if requested != "1LS 7311" {
+		t.Errorf("requested tracking number = %q, want %q", requested, "1LS 7311")
+	}
+	if result.Status != "out_for_delivery" {
+		t.Errorf("Status = %q, want out_for_delivery", result.Status)
+	}
+	if result.LastLocation != "ATLANTA, GA" {
+		t.Errorf("LastLocation = %q, want %q", result.LastLocation, "ATLANTA, GA")
+	}
+	if result.DeliveryWindowEnd == nil {
+		t.Fatal("DeliveryWindowEnd = nil, want Mar 5")
+	}
+	if month, day := result.DeliveryWindowEnd.Month(), result.DeliveryWindowEnd.Day(); month != time.March || day != 5 {
+		t.Errorf("DeliveryWindowEnd = %s, want Mar 5", result.DeliveryWindowEnd)
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		handler   http.HandlerFunc
+		permanent bool
+	}{
+		{
+			name: "unknown tracking number",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				http.NotFound(w, nil)
+			},
+			permanent: true,
+		},
+		{
+			name: "carrier unavailable",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+		},
+		{
+			name: "page changed",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = fmt.Fprint(w, `<html><body><p>Something else</p></body></html>`)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := newTestProcessor(t, tt.handler).Process(context.Background(), models.Shipment{TrackingNumber: "1LS7311"})
+			if err == nil {
+				t.Fatal("Process() error = nil")
+			}
+			if processors.IsPermanent(err) != tt.permanent {
+				t.Errorf("IsPermanent(%v) = %v, want %v", err, !tt.permanent, tt.permanent)
+			}
+			if !tt.permanent && !processors.IsRetryable(err) {
+				t.Errorf("IsRetryable(%v) = false, want true", err)
+			}
+		})
+	}
+}
+
+func TestGetStatusKey(t *testing.T) {
+	p, err := NewTrackingProcessor(newTestDefinition("https://example.com"), zap.NewNop(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Delivered", "delivered"},
+		{"Out for delivery today", "out_for_delivery"},
+		{"Scheduled for delivery", "in_transit"},
+		{"Label created", "unknown"},
+	}
+
+	for _, tt := range tests {
+		if got := p.getStatusKey(tt.text); got != tt.want {
+			t.Errorf("getStatusKey(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateWithoutYear(t *testing.T) {
+	p := &TrackingProcessor{def: models.CarrierScrapeDefinition{DateLayouts: []string{"2006-01-02", "Jan 2"}}}
+
+	tests := []struct {
+		name string
+		text string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "full date",
+			text: "2024-03-05",
+			now:  time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "this year",
+			text: "Mar 5",
+			now:  time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "next year across new year",
+			text: "Jan 3",
+			now:  time.Date(2024, time.December, 30, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2025, time.January, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "last year across new year",
+			text: "Dec 30",
+			now:  time.Date(2025, time.January, 2, 12, 0, 0, 0, time.UTC),
+			want: time.Date(2024, time.December, 30, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.parseDate(tt.text, tt.now)
+			if err != nil {
+				t.Fatalf("parseDate(%q) error = %v", tt.text, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseDate(%q) = %s, want %s", tt.text, got, tt.want)
+			}
+		})
+	}
+}
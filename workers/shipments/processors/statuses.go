@@ -0,0 +1,187 @@
+package processors
+
+import (
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// statusMappingTTL is how long a carrier's mappings are cached, and so how
+// long a newly mapped code takes to be picked up without a restart.
+const statusMappingTTL = 5 * time.Minute
+
+// StatusStore loads carrier status mappings and records codes that have none.
+type StatusStore interface {
+	GetStatusMappings(carrier string) (map[string]string, error)
+	RecordUnmappedStatus(carrier string, code string, description string, seen int) error
+}
+
+// StatusMapper resolves raw carrier status codes to status keys through the
+// StatusStore, caching each carrier's mappings. Unmapped codes are counted in
+// memory and written by Flush. A nil StatusMapper resolves every code to
+// unknown.
+type StatusMapper struct {
+	store    StatusStore
+	logger   *zap.Logger
+	mu       sync.Mutex
+	carriers map[string]*carrierStatuses
+	unmapped map[unmappedKey]*unmappedStatus
+	// warned holds codes already logged, so each is logged once per process.
+	warned map[unmappedKey]bool
+}
+
+type carrierStatuses struct {
+	// reload is held while the carrier's mappings are fetched, so a stale
+	// carrier is reloaded once and no other carrier waits on it.
+	reload   sync.Mutex
+	mappings map[string]string
+	loadedAt time.Time
+}
+
+type unmappedKey struct {
+	carrier string
+	code    string
+}
+
+type unmappedStatus struct {
+	description string
+	seen        int
+}
+
+func NewStatusMapper(store StatusStore, logger *zap.Logger) *StatusMapper {
+	return &StatusMapper{
+		store:    store,
+		logger:   logger,
+		carriers: make(map[string]*carrierStatuses),
+		unmapped: make(map[unmappedKey]*unmappedStatus),
+		warned:   make(map[unmappedKey]bool),
+	}
+}
+
+// Resolve returns the status key mapped to a carrier's raw code, or "unknown"
+// after counting the code, with its description, as unmapped.
+func (m *StatusMapper) Resolve(carrier string, code string, description string) string {
+	if m == nil || code == "" {
+		return "unknown"
+	}
+
+	if status, ok := m.load(carrier)[code]; ok {
+		return status
+	}
+
+	key := unmappedKey{carrier: carrier, code: code}
+
+	m.mu.Lock()
+	unmapped, ok := m.unmapped[key]
+	if !ok {
+		unmapped = &unmappedStatus{description: description}
+		m.unmapped[key] = unmapped
+	}
+	unmapped.seen++
+	warn := !m.warned[key]
+	m.warned[key] = true
+	m.mu.Unlock()
+
+	if warn {
+		m.logger.Warn("Unmapped carrier status",
+			zap.String("carrier", carrier),
+			zap.String("code", code),
+			zap.String("description", description),
+		)
+	}
+
+	return "unknown"
+}
+
+// Mappings returns every mapped code for carrier, for processors that match
+// codes other than by exact lookup. The map is shared and must not be
+// modified.
+func (m *StatusMapper) Mappings(carrier string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	return m.load(carrier)
+}
+
+// Flush records every unmapped code seen since the last flush, with how many
+// times it was seen. Codes that fail to record are kept for the next flush.
+func (m *StatusMapper) Flush() {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	pending := m.unmapped
+	m.unmapped = make(map[unmappedKey]*unmappedStatus)
+	m.mu.Unlock()
+
+	for key, unmapped := range pending {
+		err := m.store.RecordUnmappedStatus(key.carrier, key.code, unmapped.description, unmapped.seen)
+		if err == nil {
+			continue
+		}
+
+		m.logger.Error("Failed to record unmapped carrier status",
+			zap.String("carrier", key.carrier),
+			zap.String("code", key.code),
+			zap.Error(err),
+		)
+
+		m.mu.Lock()
+		if current, ok := m.unmapped[key]; ok {
+			current.seen += unmapped.seen
+		} else {
+			m.unmapped[key] = unmapped
+		}
+		m.mu.Unlock()
+	}
+}
+
+// load returns the cached mappings for carrier, reloading them once stale.
+// The store is only queried while the carrier's reload lock is held, never
+// mu. If reloading fails the stale mappings are kept and reloading is retried
+// on the next call.
+func (m *StatusMapper) load(carrier string) map[string]string {
+	m.mu.Lock()
+	statuses, ok := m.carriers[carrier]
+	if !ok {
+		statuses = &carrierStatuses{mappings: map[string]string{}}
+		m.carriers[carrier] = statuses
+	}
+	m.mu.Unlock()
+
+	if mappings, fresh := m.cached(statuses); fresh {
+		return mappings
+	}
+
+	statuses.reload.Lock()
+	defer statuses.reload.Unlock()
+
+	// Another caller may have reloaded the carrier while this one waited.
+	if mappings, fresh := m.cached(statuses); fresh {
+		return mappings
+	}
+
+	mappings, err := m.store.GetStatusMappings(carrier)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err != nil {
+		m.logger.Error("Failed to load carrier status mappings",
+			zap.String("carrier", carrier),
+			zap.Error(err),
+		)
+		return statuses.mappings
+	}
+
+	statuses.mappings = mappings
+	statuses.loadedAt = time.Now()
+	return mappings
+}
+
+func (m *StatusMapper) cached(statuses *carrierStatuses) (map[string]string, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return statuses.mappings, !statuses.loadedAt.IsZero() && time.Since(statuses.loadedAt) < statusMappingTTL
+}
@@ -0,0 +1,84 @@
+package processors
+
+import (
+	"errors"
+	"go.uber.org/zap"
+	"personal-homepage-service/workers/shipments/processors/processorstest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStatusMapperCountsUnmappedSightings(t *testing.T) {
+	store := processorstest.NewStatusStore(map[string]map[string]string{
+		"ups": {"D": "delivered"},
+	})
+	m := NewStatusMapper(store, zap.NewNop())
+
+	if got := m.Resolve("ups", "D", "Delivered"); got != "delivered" {
+		t.Fatalf("Resolve(D) = %q, want delivered", got)
+	}
+	for range 3 {
+		if got := m.Resolve("ups", "X", "Exception"); got != "unknown" {
+			t.Fatalf("Resolve(X) = %q, want unknown", got)
+		}
+	}
+
+	// A failed write keeps the count for the next flush.
+	store.FailRecording(errors.New("connection refused"))
+	m.Flush()
+	store.FailRecording(nil)
+
+	m.Resolve("ups", "X", "Exception")
+	m.Flush()
+
+	want := []processorstest.UnmappedStatus{{Carrier: "ups", Code: "X", Description: "Exception", Seen: 4}}
+	if got := store.Recorded(); !slices.Equal(got, want) {
+		t.Fatalf("recorded %v, want %v", got, want)
+	}
+
+	m.Flush()
+	if got := store.Recorded(); !slices.Equal(got, want) {
+		t.Errorf("recorded %v after an empty flush, want %v", got, want)
+	}
+}
+
+func TestStatusMapperLoadDoesNotBlockOtherCarriers(t *testing.T) {
+	store := processorstest.NewStatusStore(map[string]map[string]string{
+		"ups":   {"D": "delivered"},
+		"fedex": {"DL": "delivered"},
+	})
+	release := store.Block("ups")
+	m := NewStatusMapper(store, zap.NewNop())
+
+	done := make(chan string)
+	go func() { done <- m.Resolve("ups", "D", "Delivered") }()
+
+	resolved := make(chan string)
+	go func() { resolved <- m.Resolve("fedex", "DL", "Delivered") }()
+
+	select {
+	case got := <-resolved:
+		if got != "delivered" {
+			t.Errorf("Resolve(fedex, DL) = %q, want delivered", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Resolve(fedex) waited on the ups load")
+	}
+
+	release()
+	if got := <-done; got != "delivered" {
+		t.Errorf("Resolve(ups, D) = %q, want delivered", got)
+	}
+}
+
+func TestNilStatusMapper(t *testing.T) {
+	var m *StatusMapper
+	if got := m.Resolve("ups", "D", "Delivered"); got != "unknown" {
+		t.Errorf("Resolve() = %q, want unknown", got)
+	}
+	if got := m.Mappings("ups"); got != nil {
+		t.Errorf("Mappings() = %v, want nil", got)
+	}
+	m.Flush()
+}
@@ -0,0 +1,26 @@
+package processors
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TitleCase turns the upper case place names carriers return, such as
+// "SALT LAKE CITY" or "WINSTON-SALEM", into "Salt Lake City" and
+// "Winston-Salem". It works on runes, so accented names like "ÉVRY" come out
+// as "Évry" rather than being cut mid-character.
+func TitleCase(s string) string {
+	var b strings.Builder
+	wordStart := true
+
+	for _, r := range strings.Join(strings.Fields(s), " ") {
+		if wordStart {
+			b.WriteRune(unicode.ToTitle(r))
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+		wordStart = r == ' ' || r == '-'
+	}
+
+	return b.String()
+}
@@ -0,0 +1,25 @@
+package processors
+
+import "testing"
+
+func TestTitleCase(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"MEMPHIS", "Memphis"},
+		{"salt lake city", "Salt Lake City"},
+		{"  NEW   YORK ", "New York"},
+		{"WINSTON-SALEM", "Winston-Salem"},
+		{"ÉVRY", "Évry"},
+		{"SÃO PAULO", "São Paulo"},
+		{"KÖLN", "Köln"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := TitleCase(tt.in); got != tt.want {
+			t.Errorf("TitleCase(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
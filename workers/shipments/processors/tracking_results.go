@@ -9,4 +9,18 @@ type CarrierTrackingResults struct {
 	LastLocation        string
 	LastCheckedAt       *time.Time
 	Status              string
+	// HandoffTrackingNumber is set when another carrier has taken over
+	// delivery under its own tracking number.
+	HandoffTrackingNumber string
+	Events                []CarrierTrackingEvent
+}
+
+// CarrierTrackingEvent is a single scan or update from the carrier's
+// timeline. Status holds our normalized status key for the event.
+type CarrierTrackingEvent struct {
+	OccurredAt        time.Time
+	Location          string
+	CarrierStatusCode string
+	Description       string
+	Status            string
 }
@@ -1,9 +1,13 @@
 package uds
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/gocolly/colly/v2"
 	"go.uber.org/zap"
+	"net/http"
+	"personal-homepage-service/metrics"
 	"personal-homepage-service/workers/shipments/models"
 	"personal-homepage-service/workers/shipments/processors"
 	"regexp"
@@ -12,22 +16,26 @@ import (
 	"time"
 )
 
-var statusMap = map[string]string{
-	"Shipment Notification": "pending",
-	"Received":              "in_transit",
-	"Out for Delivery":      "out_for_delivery",
-	"Delivered":             "delivered",
+type TrackingProcessor struct {
+	logger   *zap.Logger
+	client   *http.Client
+	statuses *processors.StatusMapper
 }
 
-type TrackingProcessor struct {
-	logger *zap.Logger
+func NewTrackingProcessor(logger *zap.Logger, client *http.Client, statuses *processors.StatusMapper) *TrackingProcessor {
+	return &TrackingProcessor{logger, client, statuses}
 }
 
-func NewTrackingProcessor(logger *zap.Logger) *TrackingProcessor {
-	return &TrackingProcessor{logger}
+func Register(r *processors.Registry) {
+	r.Register(processors.Carrier{
+		Key: "uds",
+		New: func(deps processors.Dependencies) processors.CarrierTrackingProcessor {
+			return NewTrackingProcessor(deps.Logger, deps.HTTPClient, deps.Statuses)
+		},
+	})
 }
 
-func (p *TrackingProcessor) Process(shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
+func (p *TrackingProcessor) Process(ctx context.Context, shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
 	trackingNumber := shipment.TrackingNumber
 	url := shipment.TrackingURL
 	now := time.Now()
@@ -35,7 +43,13 @@ func (p *TrackingProcessor) Process(shipment models.Shipment) (*processors.Carri
 	lastLoc := shipment.LastLocation
 	expected := shipment.DeliveryWindowEnd
 
-	c := colly.NewCollector()
+	if url == "" {
+		return nil, processors.PermanentError(errors.New("shipment has no tracking URL"))
+	}
+
+	c := colly.NewCollector(colly.StdlibContext(metrics.WithOperation(ctx, "track")))
+	c.WithTransport(p.client.Transport)
+	c.SetRequestTimeout(p.client.Timeout)
 
 	wg := &sync.WaitGroup{}
 	wg.Add(1)
@@ -118,12 +132,20 @@ func (p *TrackingProcessor) Process(shipment models.Shipment) (*processors.Carri
 		}
 	})
 
+	statusCode := 0
+	c.OnError(func(r *colly.Response, _ error) {
+		statusCode = r.StatusCode
+	})
+
 	c.OnScraped(func(r *colly.Response) {
 		wg.Done()
 	})
 
 	if err := c.Visit(url); err != nil {
-		return nil, err
+		if statusCode != 0 {
+			return nil, processors.StatusError(statusCode, err.Error())
+		}
+		return nil, processors.RequestError(err)
 	}
 
 	wg.Wait()
@@ -133,14 +155,6 @@ func (p *TrackingProcessor) Process(shipment models.Shipment) (*processors.Carri
 		DeliveryWindowEnd: expected,
 		LastLocation:      lastLoc,
 		LastCheckedAt:     &now,
-		Status:            getStatusKey(title),
+		Status:            p.statuses.Resolve("uds", title, title),
 	}, nil
 }
-
-func getStatusKey(title string) string {
-	status, ok := statusMap[title]
-	if !ok {
-		return "unknown"
-	}
-	return status
-}
@@ -1,6 +1,7 @@
 package unsupported
 
 import (
+	"context"
 	"go.uber.org/zap"
 	"personal-homepage-service/workers/shipments/models"
 	"personal-homepage-service/workers/shipments/processors"
@@ -15,7 +16,12 @@ func NewTrackingProcessor(logger *zap.Logger) *TrackingProcessor {
 	return &TrackingProcessor{logger}
 }
 
-func (p *TrackingProcessor) Process(shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
+// Factory builds the processor used for carriers with no registration.
+func Factory(deps processors.Dependencies) processors.CarrierTrackingProcessor {
+	return NewTrackingProcessor(deps.Logger)
+}
+
+func (p *TrackingProcessor) Process(_ context.Context, shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
 	now := time.Now()
 
 	return &processors.CarrierTrackingResults{
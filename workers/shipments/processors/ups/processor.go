@@ -1,8 +1,10 @@
 package ups
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
@@ -10,80 +12,59 @@ import (
 	"net/http"
 	"net/url"
 	"personal-homepage-service/config"
+	"personal-homepage-service/metrics"
 	"personal-homepage-service/workers/shipments/models"
 	"personal-homepage-service/workers/shipments/processors"
+	"personal-homepage-service/workers/shipments/processors/oauth"
+	"strconv"
 	"strings"
 	"time"
 )
 
-var upsCodeMap = map[string]string{
-	"003": "pending",            // Shipment Ready for UPS
-	"005": "accepted",           // In Transit
-	"006": "out_for_delivery",   // Out for Delivery Today
-	"007": "cancelled",          // Shipment Canceled
-	"011": "delivered",          // Delivered
-	"012": "in_transit",         // Clearance in Progress
-	"013": "in_transit",         // Update
-	"014": "in_transit",         // Cleared Customs
-	"016": "exception",          // Held in Warehouse
-	"017": "delivered",          // Held for Customer Pickup
-	"018": "exception",          // Hold for Pickup Requested
-	"019": "delayed",            // Delivery Rescheduled
-	"021": "out_for_delivery",   // Out for Delivery Today
-	"022": "attempted_delivery", // Delivery Attempted
-	"023": "attempted_delivery", // Delivery Attempted
-	"024": "attempted_delivery", // IsFinal Delivery Attempt Made
-	"025": "in_transit",         // In Transit
-	"026": "delivered",          // Delivered by Local Post Office
-	"027": "in_transit",         // Address Change Requested
-	"028": "in_transit",         // Delivery Address Changed
-	"029": "exception",          // Address Information Required
-	"030": "delayed",            // Local Post Office Delay
-	"032": "delayed",            // Weather May Cause Delay
-	"033": "returned",           // Return Requested
-	"035": "returned",           // Returning to Sender
-	"038": "accepted",           // Picked Up
-	"040": "delivered",          // Delivered to UPS Access Point™
-	"042": "in_transit",         // Service Upgraded
-	"044": "in_transit",         // On Its Way to UPS
-	"045": "in_transit",         // Order Processed: On its Way to UPS
-	"046": "delayed",            // Delay
-	"047": "in_transit",         // In Transit
-	"048": "delayed",            // Delay
-	"049": "exception",          // Delay: Attention Needed
-	"050": "exception",          // Address Information Required
-	"051": "delayed",            // Delay: Emergency Situation or Severe Weather
-	"052": "delayed",            // Severe Weather Delay
-	"053": "delayed",            // Severe Weather Delay
-	"054": "delayed",            // Delivery Change Requested
-	"055": "delayed",            // Rescheduled Delivery
-	"057": "in_transit",         // On Its Way to a Local UPS Access Point™
-	"058": "exception",          // Clearance Information Required
-	"065": "attempted_delivery", // Pickup Attempted
-	"070": "in_transit",         // On Its Way to a Local UPS Access Point™
-	"071": "out_for_delivery",   // Preparing for Delivery Today
-	"072": "out_for_delivery",   // Loaded on Delivery Vehicle
-	"077": "delivered",          // Scheduled for Pickup Today
+type TrackingProcessor struct {
+	config   *config.UpsApiConfig
+	logger   *zap.Logger
+	client   *http.Client
+	statuses *processors.StatusMapper
+	tokens   oauth.TokenCache
 }
 
-type TrackingProcessor struct {
-	config *config.UpsApiConfig
-	logger *zap.Logger
+func NewTrackingProcessor(cfg *config.UpsApiConfig, logger *zap.Logger, client *http.Client, statuses *processors.StatusMapper) *TrackingProcessor {
+	return &TrackingProcessor{config: cfg, logger: logger, client: client, statuses: statuses}
 }
 
-func NewTrackingProcessor(logger *zap.Logger) *TrackingProcessor {
-	cfg := config.LoadConfig()
-	return &TrackingProcessor{cfg.UPSApi, logger}
+func Register(r *processors.Registry) {
+	r.Register(processors.Carrier{
+		Key: "ups",
+		New: func(deps processors.Dependencies) processors.CarrierTrackingProcessor {
+			return NewTrackingProcessor(deps.Config.UPSApi, deps.Logger, deps.HTTPClient, deps.Statuses)
+		},
+		Validate: validateConfig,
+	})
 }
 
-func (p *TrackingProcessor) Process(shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
+func validateConfig(cfg *config.Config) error {
+	if cfg.UPSApi == nil || cfg.UPSApi.BaseUri == "" {
+		return errors.New("UPS_API_BASE_URI is not set")
+	}
+	if cfg.UPSApi.ClientId == "" || cfg.UPSApi.ClientSecret == "" {
+		return errors.New("UPS_API_CLIENT_ID and UPS_API_CLIENT_SECRET must be set")
+	}
+	return nil
+}
+
+func (p *TrackingProcessor) Process(ctx context.Context, shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
 	trackingNumber := shipment.TrackingNumber
-	details, err := p.getTrackingDetails(trackingNumber)
+	details, err := p.getTrackingDetails(ctx, trackingNumber)
 	if err != nil {
 		return nil, err
 	}
 	now := time.Now()
 
+	if len(details.Response.Shipments) == 0 {
+		return nil, processors.PermanentError(fmt.Errorf("no shipment returned for %s", trackingNumber))
+	}
+
 	shp := details.Response.Shipments[0]
 
 	if len(shp.Packages) == 0 {
@@ -106,10 +87,69 @@ func (p *TrackingProcessor) Process(shipment models.Shipment) (*processors.Carri
 		DeliveryWindowEnd:   delEnd,
 		LastLocation:        getLastLocation(pkg.Activity),
 		LastCheckedAt:       &now,
-		Status:              getStatusKey(pkg.CurrentStatus.Code),
+		Status:              p.getStatusKey(pkg.CurrentStatus.Code, pkg.CurrentStatus.Description),
+		Events:              p.getEvents(pkg.Activity),
 	}, nil
 }
 
+func (p *TrackingProcessor) getEvents(activity []Activity) []processors.CarrierTrackingEvent {
+	events := make([]processors.CarrierTrackingEvent, 0, len(activity))
+
+	for _, a := range activity {
+		occurredAt, err := parseActivityTime(a)
+		if err != nil {
+			p.logger.Warn("Skipping activity with unparseable time",
+				zap.String("date", a.Date),
+				zap.String("time", a.Time),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		code := a.Status.StatusCode
+		if code == "" {
+			code = a.Status.Code
+		}
+
+		events = append(events, processors.CarrierTrackingEvent{
+			OccurredAt:        occurredAt,
+			Location:          formatLocation(a.Location),
+			CarrierStatusCode: code,
+			Description:       a.Status.Description,
+			Status:            p.getStatusKey(code, a.Status.Description),
+		})
+	}
+
+	return events
+}
+
+// parseActivityTime reads the GMT date and time of an activity and presents
+// it in the local offset of the scan.
+func parseActivityTime(a Activity) (time.Time, error) {
+	timeStr := strings.ReplaceAll(a.Time, ":", "")
+
+	t, err := time.ParseInLocation("20060102150405", a.Date+timeStr, time.UTC)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if offset, err := parseOffset(a.TimeZoneOffset); err == nil {
+		t = t.In(time.FixedZone(a.TimeZoneOffset, offset))
+	}
+
+	return t, nil
+}
+
+// parseOffset converts offsets such as "-04:00" to seconds east of UTC.
+func parseOffset(offset string) (int, error) {
+	t, err := time.Parse("-07:00", offset)
+	if err != nil {
+		return 0, err
+	}
+	_, seconds := t.Zone()
+	return seconds, nil
+}
+
 func getExpectedDeliveryWindow(p Package) (*time.Time, *time.Time, error) {
 	if len(p.DeliveryDate) == 0 {
 		return nil, nil, nil
@@ -155,26 +195,29 @@ func parseDatetime(date string, timeStr string) (*time.Time, error) {
 }
 
 func getLastLocation(activity []Activity) string {
-	lastLocation := activity[0].Location
-	region := lastLocation.Address.CountryCode
+	if len(activity) == 0 {
+		return ""
+	}
+
+	return formatLocation(activity[0].Location)
+}
 
-	if lastLocation.Address.City == "" {
+func formatLocation(location Location) string {
+	region := location.Address.CountryCode
+
+	if location.Address.City == "" {
 		return region
 	}
 
-	if lastLocation.Address.CountryCode == "US" {
-		region = lastLocation.Address.State
+	if location.Address.CountryCode == "US" {
+		region = location.Address.State
 	}
 
-	return lastLocation.Address.City + ", " + region
+	return location.Address.City + ", " + region
 }
 
-func getStatusKey(code string) string {
-	status, ok := upsCodeMap[code]
-	if !ok {
-		return "unknown"
-	}
-	return status
+func (p *TrackingProcessor) getStatusKey(code string, description string) string {
+	return p.statuses.Resolve("ups", code, description)
 }
 
 func basicAuth(username, password string) string {
@@ -182,52 +225,71 @@ func basicAuth(username, password string) string {
 	return base64.StdEncoding.EncodeToString([]byte(auth))
 }
 
-func (p *TrackingProcessor) getAccessToken() (string, error) {
+func (p *TrackingProcessor) getAccessToken(ctx context.Context) (string, error) {
+	return p.tokens.Get(ctx, p.requestAccessToken)
+}
+
+func (p *TrackingProcessor) requestAccessToken(ctx context.Context) (token *oauth.Token, err error) {
+	defer func() {
+		metrics.ObserveTokenRefresh("ups", err)
+	}()
+
 	u, err := url.Parse(p.config.BaseUri + "/security/v1/oauth/token")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	data := url.Values{}
 	data.Set("grant_type", "client_credentials")
 
-	req, err := http.NewRequest("POST", u.String(), strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(metrics.WithOperation(ctx, "oauth"), "POST", u.String(), strings.NewReader(data.Encode()))
 
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
 	req.Header.Set("Authorization", "Basic "+basicAuth(p.config.ClientId, p.config.ClientSecret))
 
-	client := &http.Client{Timeout: 20 * time.Second}
-	resp, err2 := client.Do(req)
-	if err2 != nil {
-		panic(err2)
+	httpResp, err := p.client.Do(req)
+	if err != nil {
+		return nil, processors.RequestError(err)
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
-	}(resp.Body)
+	}(httpResp.Body)
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+	if httpResp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(httpResp.Body)
+		return nil, processors.StatusError(httpResp.StatusCode, string(bodyBytes))
 	}
 
 	var authResponse OAuthResponse
-	if err := json.NewDecoder(resp.Body).Decode(&authResponse); err != nil {
-		return "", fmt.Errorf("failed to decode response: %w", err)
+	if err := json.NewDecoder(httpResp.Body).Decode(&authResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return authResponse.AccessToken, nil
+	return &oauth.Token{
+		AccessToken: authResponse.AccessToken,
+		ExpiresIn:   expiresIn(authResponse.ExpiresIn),
+	}, nil
+}
+
+// expiresIn parses the expires_in seconds UPS returns as a string. Anything
+// unparseable is treated as already expired so the token is used only once.
+func expiresIn(raw string) time.Duration {
+	seconds, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0
+	}
+	return oauth.Seconds(seconds)
 }
 
-func (p *TrackingProcessor) getTrackingDetails(trackingNumber string) (*ApiResponse, error) {
+func (p *TrackingProcessor) getTrackingDetails(ctx context.Context, trackingNumber string) (*ApiResponse, error) {
 	endpoint := "/api/track/v1/details/" + trackingNumber
 
 	u, err := url.Parse(p.config.BaseUri + endpoint)
 	if err != nil {
-		fmt.Println("URL parse error:", err)
 		return nil, err
 	}
 
@@ -238,15 +300,33 @@ func (p *TrackingProcessor) getTrackingDetails(trackingNumber string) (*ApiRespo
 	q.Set("returnPOD", "false")
 	u.RawQuery = q.Encode()
 
-	req, err := http.NewRequest("GET", u.String(), nil)
+	accessToken, err := p.getAccessToken(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	accessToken, tokenErr := p.getAccessToken()
+	details, status, err := p.requestTrackingDetails(ctx, u.String(), accessToken)
 
-	if tokenErr != nil {
-		return nil, tokenErr
+	// A 401 means the cached token was revoked or expired early; refresh it
+	// once and retry.
+	if status == http.StatusUnauthorized {
+		p.tokens.Invalidate(accessToken)
+
+		accessToken, err = p.getAccessToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		details, _, err = p.requestTrackingDetails(ctx, u.String(), accessToken)
+	}
+
+	return details, err
+}
+
+func (p *TrackingProcessor) requestTrackingDetails(ctx context.Context, endpoint string, accessToken string) (*ApiResponse, int, error) {
+	req, err := http.NewRequestWithContext(metrics.WithOperation(ctx, "track"), "GET", endpoint, nil)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -254,10 +334,9 @@ func (p *TrackingProcessor) getTrackingDetails(trackingNumber string) (*ApiRespo
 	req.Header.Set("transId", uuid.New().String())
 	req.Header.Set("transactionSrc", "personal_homepage")
 
-	client := &http.Client{Timeout: 20 * time.Second}
-	resp, clientErr := client.Do(req)
+	resp, clientErr := p.client.Do(req)
 	if clientErr != nil {
-		panic(clientErr)
+		return nil, 0, processors.RequestError(clientErr)
 	}
 	defer func(Body io.ReadCloser) {
 		_ = Body.Close()
@@ -265,14 +344,13 @@ func (p *TrackingProcessor) getTrackingDetails(trackingNumber string) (*ApiRespo
 
 	if resp.StatusCode != http.StatusOK {
 		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(bodyBytes))
+		return nil, resp.StatusCode, processors.StatusError(resp.StatusCode, string(bodyBytes))
 	}
 
 	var apiResponse ApiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+		return nil, resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
 	}
 
-	return &apiResponse, nil
-
+	return &apiResponse, resp.StatusCode, nil
 }
@@ -0,0 +1,365 @@
+package usps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/url"
+	"personal-homepage-service/config"
+	"personal-homepage-service/metrics"
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"personal-homepage-service/workers/shipments/processors/oauth"
+	"strings"
+	"time"
+)
+
+// categoryCodePrefix marks the carrier_status_mappings codes that map the
+// statusCategory summary, lower cased, rather than an event code. Categories
+// are used when the latest event code is missing or not mapped.
+const categoryCodePrefix = "category:"
+
+// notInSystem appears in the status of labels that have been printed but not
+// yet scanned by USPS.
+const notInSystem = "not yet in system"
+
+type TrackingProcessor struct {
+	config   *config.UspsApiConfig
+	logger   *zap.Logger
+	client   *http.Client
+	statuses *processors.StatusMapper
+	tokens   oauth.TokenCache
+}
+
+func NewTrackingProcessor(cfg *config.UspsApiConfig, logger *zap.Logger, client *http.Client, statuses *processors.StatusMapper) *TrackingProcessor {
+	return &TrackingProcessor{config: cfg, logger: logger, client: client, statuses: statuses}
+}
+
+func Register(r *processors.Registry) {
+	r.Register(processors.Carrier{
+		Key: "usps",
+		New: func(deps processors.Dependencies) processors.CarrierTrackingProcessor {
+			return NewTrackingProcessor(deps.Config.USPSApi, deps.Logger, deps.HTTPClient, deps.Statuses)
+		},
+		Validate: validateConfig,
+	})
+}
+
+func validateConfig(cfg *config.Config) error {
+	if cfg.USPSApi == nil || cfg.USPSApi.BaseUri == "" {
+		return errors.New("USPS_API_BASE_URI is not set")
+	}
+	if cfg.USPSApi.ClientId == "" || cfg.USPSApi.ClientSecret == "" {
+		return errors.New("USPS_API_CLIENT_ID and USPS_API_CLIENT_SECRET must be set")
+	}
+	return nil
+}
+
+func (p *TrackingProcessor) Process(ctx context.Context, shipment models.Shipment) (*processors.CarrierTrackingResults, error) {
+	trackingNumber := shipment.TrackingNumber
+	details, err := p.getTrackingDetails(ctx, trackingNumber)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+
+	if isNotInSystem(details) {
+		return &processors.CarrierTrackingResults{
+			TrackingNumber: trackingNumber,
+			LastCheckedAt:  &now,
+			Status:         "pending",
+		}, nil
+	}
+
+	delStart, delEnd, delErr := getExpectedDeliveryWindow(details)
+	if delErr != nil {
+		p.logger.Error("Error parsing datetime:" + delErr.Error())
+	}
+
+	return &processors.CarrierTrackingResults{
+		TrackingNumber:      trackingNumber,
+		DeliveryWindowStart: delStart,
+		DeliveryWindowEnd:   delEnd,
+		LastLocation:        getLastLocation(details.TrackingEvents),
+		LastCheckedAt:       &now,
+		Status:              p.getStatusKey(details),
+		Events:              p.getEvents(details.TrackingEvents),
+	}, nil
+}
+
+func isNotInSystem(details *ApiResponse) bool {
+	return len(details.TrackingEvents) == 0 &&
+		(strings.Contains(strings.ToLower(details.Status), notInSystem) ||
+			strings.Contains(strings.ToLower(details.StatusSummary), notInSystem))
+}
+
+func (p *TrackingProcessor) getEvents(trackingEvents []TrackingEvent) []processors.CarrierTrackingEvent {
+	events := make([]processors.CarrierTrackingEvent, 0, len(trackingEvents))
+
+	for _, e := range trackingEvents {
+		occurredAt, err := parseEventTime(e)
+		if err != nil {
+			p.logger.Warn("Skipping tracking event with unparseable time",
+				zap.String("timestamp", e.EventTimestamp),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		events = append(events, processors.CarrierTrackingEvent{
+			OccurredAt:        occurredAt,
+			Location:          formatLocation(e),
+			CarrierStatusCode: e.EventCode,
+			Description:       e.EventType,
+			Status:            p.statuses.Resolve("usps", e.EventCode, e.EventType),
+		})
+	}
+
+	return events
+}
+
+// parseEventTime reads the GMT timestamp of an event and presents it in the
+// local offset of the scan, falling back to the local timestamp.
+func parseEventTime(e TrackingEvent) (time.Time, error) {
+	if e.GMTTimestamp != "" {
+		t, err := parseDatetime(e.GMTTimestamp, time.UTC)
+		if err == nil {
+			if offset, err := parseOffset(e.GMTOffset); err == nil {
+				return t.In(time.FixedZone(e.GMTOffset, offset)), nil
+			}
+			return *t, nil
+		}
+	}
+
+	t, err := parseDatetime(e.EventTimestamp, time.Now().Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	return *t, nil
+}
+
+// parseOffset converts offsets such as "-04:00" to seconds east of UTC.
+func parseOffset(offset string) (int, error) {
+	t, err := time.Parse("-07:00", offset)
+	if err != nil {
+		return 0, err
+	}
+	_, seconds := t.Zone()
+	return seconds, nil
+}
+
+// getExpectedDeliveryWindow prefers the predicted delivery window, then the
+// expected and guaranteed delivery times. A bare date covers the whole day.
+func getExpectedDeliveryWindow(details *ApiResponse) (*time.Time, *time.Time, error) {
+	loc := time.Now().Location()
+
+	if date := details.PredictedDeliveryDate; date != "" {
+		day := strings.SplitN(date, "T", 2)[0]
+
+		startTime, endTime := "00:00:00", "23:59:59"
+		if details.PredictedDeliveryWindowStartTime != "" && details.PredictedDeliveryWindowEndTime != "" {
+			startTime, endTime = details.PredictedDeliveryWindowStartTime, details.PredictedDeliveryWindowEndTime
+		}
+
+		end, endErr := parseDatetime(day+"T"+endTime, loc)
+		if endErr != nil {
+			return nil, nil, endErr
+		}
+		start, startErr := parseDatetime(day+"T"+startTime, loc)
+		if startErr != nil {
+			return nil, end, startErr
+		}
+		return start, end, nil
+	}
+
+	for _, timestamp := range []string{details.ExpectedDeliveryTimeStamp, details.GuaranteedDeliveryTimeStamp} {
+		if timestamp == "" {
+			continue
+		}
+
+		if !strings.Contains(timestamp, "T") {
+			timestamp += "T23:59:59"
+		}
+
+		end, err := parseDatetime(timestamp, loc)
+		return nil, end, err
+	}
+
+	return nil, nil, nil
+}
+
+// parseDatetime reads USPS timestamps, which may or may not carry an offset.
+// Timestamps without one are read in loc.
+func parseDatetime(value string, loc *time.Location) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	for _, layout := range []string{"2006-01-02T15:04:05", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return &t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unrecognized timestamp %q", value)
+}
+
+func getLastLocation(events []TrackingEvent) string {
+	for _, e := range events {
+		if location := formatLocation(e); location != "" {
+			return location
+		}
+	}
+	return ""
+}
+
+func formatLocation(e TrackingEvent) string {
+	region := e.EventCountry
+	if region == "" || region == "US" || region == "UNITED STATES" {
+		region = e.EventState
+	}
+
+	if e.EventCity == "" {
+		return region
+	}
+
+	city := processors.TitleCase(e.EventCity)
+	if region == "" {
+		return city
+	}
+	return city + ", " + region
+}
+
+// getStatusKey resolves the shipment status from the latest event code,
+// falling back to the status category USPS summarizes it as.
+func (p *TrackingProcessor) getStatusKey(details *ApiResponse) string {
+	if len(details.TrackingEvents) > 0 {
+		latest := details.TrackingEvents[0]
+		if status := p.statuses.Resolve("usps", latest.EventCode, latest.EventType); status != "unknown" {
+			return status
+		}
+	}
+
+	category := strings.ToLower(strings.TrimSpace(details.StatusCategory))
+	if category == "" {
+		return "unknown"
+	}
+	return p.statuses.Resolve("usps", categoryCodePrefix+category, details.StatusCategory)
+}
+
+func (p *TrackingProcessor) getAccessToken(ctx context.Context) (string, error) {
+	return p.tokens.Get(ctx, p.requestAccessToken)
+}
+
+func (p *TrackingProcessor) requestAccessToken(ctx context.Context) (token *oauth.Token, err error) {
+	defer func() {
+		metrics.ObserveTokenRefresh("usps", err)
+	}()
+
+	body, err := json.Marshal(OAuthRequest{
+		GrantType:    "client_credentials",
+		ClientId:     p.config.ClientId,
+		ClientSecret: p.config.ClientSecret,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(metrics.WithOperation(ctx, "oauth"), "POST", p.config.BaseUri+"/oauth2/v3/token", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	httpResp, err := p.client.Do(req)
+	if err != nil {
+		return nil, processors.RequestError(err)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(httpResp.Body)
+
+	if httpResp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(httpResp.Body)
+		return nil, processors.StatusError(httpResp.StatusCode, string(bodyBytes))
+	}
+
+	var authResponse OAuthResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&authResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &oauth.Token{
+		AccessToken: authResponse.AccessToken,
+		ExpiresIn:   oauth.Seconds(authResponse.ExpiresIn),
+	}, nil
+}
+
+func (p *TrackingProcessor) getTrackingDetails(ctx context.Context, trackingNumber string) (*ApiResponse, error) {
+	u, err := url.Parse(p.config.BaseUri + "/tracking/v3/tracking/" + url.PathEscape(trackingNumber))
+	if err != nil {
+		return nil, err
+	}
+
+	q := u.Query()
+	q.Set("expand", "DETAIL")
+	u.RawQuery = q.Encode()
+
+	accessToken, err := p.getAccessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	details, status, err := p.requestTrackingDetails(ctx, u.String(), accessToken)
+
+	// A 401 means the cached token was revoked or expired early; refresh it
+	// once and retry.
+	if status == http.StatusUnauthorized {
+		p.tokens.Invalidate(accessToken)
+
+		accessToken, err = p.getAccessToken(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		details, _, err = p.requestTrackingDetails(ctx, u.String(), accessToken)
+	}
+
+	return details, err
+}
+
+func (p *TrackingProcessor) requestTrackingDetails(ctx context.Context, endpoint string, accessToken string) (*ApiResponse, int, error) {
+	req, err := http.NewRequestWithContext(metrics.WithOperation(ctx, "track"), "GET", endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, clientErr := p.client.Do(req)
+	if clientErr != nil {
+		return nil, 0, processors.RequestError(clientErr)
+	}
+	defer func(Body io.ReadCloser) {
+		_ = Body.Close()
+	}(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, resp.StatusCode, processors.StatusError(resp.StatusCode, string(bodyBytes))
+	}
+
+	var apiResponse ApiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResponse); err != nil {
+		return nil, resp.StatusCode, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &apiResponse, resp.StatusCode, nil
+}
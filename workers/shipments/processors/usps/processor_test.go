@@ -0,0 +1,307 @@
+package usps
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"personal-homepage-service/config"
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"personal-homepage-service/workers/shipments/processors/processorstest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeUSPS serves the OAuth and Tracking endpoints, handing out numbered
+// tokens and answering tracking requests with response.
+type fakeUSPS struct {
+	t        *testing.T
+	mu       sync.Mutex
+	tokens   int
+	tracked  []string
+	response ApiResponse
+}
+
+func (f *fakeUSPS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/oauth2/v3/token":
+		var req OAuthRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ClientId != "client-id" || req.GrantType != "client_credentials" {
+			f.t.Errorf("unexpected token request: %+v (%v)", req, err)
+		}
+		f.tokens++
+		_ = json.NewEncoder(w).Encode(OAuthResponse{
+			AccessToken: fmt.Sprintf("token-%d", f.tokens),
+			ExpiresIn:   3600,
+		})
+	case strings.HasPrefix(r.URL.Path, "/tracking/v3/tracking/"):
+		if got := r.URL.Query().Get("expand"); got != "DETAIL" {
+			f.t.Errorf("expand = %q, want DETAIL", got)
+		}
+		if got := r.Header.Get("Authorization"); !strings.HasPrefix(got, "Bearer token-") {
+			f.t.Errorf("Authorization = %q, want a bearer token", got)
+		}
+		f.tracked = append(f.tracked, strings.TrimPrefix(r.URL.Path, "/tracking/v3/tracking/"))
+		_ = json.NewEncoder(w).Encode(f.response)
+	default:
+		f.t.Errorf("unexpected request to %s", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestProcessor(t *testing.T, fake *fakeUSPS, store *processorstest.StatusStore) *TrackingProcessor {
+	t.Helper()
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	cfg := &config.UspsApiConfig{BaseUri: srv.URL, ClientId: "client-id", ClientSecret: "client-secret"}
+	return NewTrackingProcessor(cfg, zap.NewNop(), srv.Client(), processors.NewStatusMapper(store, zap.NewNop()))
+}
+
+func newTestStore() *processorstest.StatusStore {
+	return processorstest.NewStatusStore(map[string]map[string]string{
+		"usps": {
+			"03":                            "accepted",
+			"10":                            "in_transit",
+			"OF":                            "out_for_delivery",
+			"category:in transit":           "in_transit",
+			"category:available for pickup": "awaiting_pickup",
+		},
+	})
+}
+
+var shipment = models.Shipment{TrackingNumber: "9205590164917312751089"}
+
+func TestProcess(t *testing.T) {
+	fake := &fakeUSPS{
+		t: t,
+		response: ApiResponse{
+			TrackingNumber:                   "9205590164917312751089",
+			StatusCategory:                   "In Transit",
+			PredictedDeliveryDate:            "2024-03-05",
+			PredictedDeliveryWindowStartTime: "09:00:00",
+			PredictedDeliveryWindowEndTime:   "13:00:00",
+			TrackingEvents: []TrackingEvent{
+				{
+					EventType:    "Out for Delivery",
+					EventCode:    "OF",
+					GMTTimestamp: "2024-03-05T12:10:00Z",
+					GMTOffset:    "-07:00",
+					EventCity:    "SALT LAKE CITY",
+					EventState:   "UT",
+				},
+				{
+					EventType:      "Arrived at USPS Regional Facility",
+					EventCode:      "10",
+					EventTimestamp: "2024-03-04T22:15:00",
+					EventCity:      "TORONTO",
+					EventCountry:   "CANADA",
+				},
+				{
+					EventType:      "Accepted at USPS Origin Facility",
+					EventCode:      "03",
+					EventTimestamp: "not a timestamp",
+				},
+			},
+		},
+	}
+	p := newTestProcessor(t, fake, newTestStore())
+
+	for range 2 {
+		result, err := p.Process(context.Background(), shipment)
+		if err != nil {
+			t.Fatalf("Process() error = %v", err)
+		}
+
+		if result.Status != "out_for_delivery" {
+			t.Errorf("Status = %q, want out_for_delivery", result.Status)
+		}
+		if result.LastLocation != "Salt Lake City, UT" {
+			t.Errorf("LastLocation = %q, want %q", result.LastLocation, "Salt Lake City, UT")
+		}
+		assertTime(t, "DeliveryWindowStart", result.DeliveryWindowStart, time.Date(2024, time.March, 5, 9, 0, 0, 0, time.Local))
+		assertTime(t, "DeliveryWindowEnd", result.DeliveryWindowEnd, time.Date(2024, time.March, 5, 13, 0, 0, 0, time.Local))
+
+		// The event with an unparseable time is skipped.
+		if len(result.Events) != 2 {
+			t.Fatalf("got %d events, want 2", len(result.Events))
+		}
+
+		latest := result.Events[0]
+		if _, offset := latest.OccurredAt.Zone(); offset != -7*60*60 {
+			t.Errorf("event offset = %d, want %d", offset, -7*60*60)
+		}
+		if want := time.Date(2024, time.March, 5, 12, 10, 0, 0, time.UTC); !latest.OccurredAt.Equal(want) {
+			t.Errorf("event OccurredAt = %s, want %s", latest.OccurredAt, want)
+		}
+		if got := result.Events[1]; got.Location != "Toronto, CANADA" || got.Status != "in_transit" {
+			t.Errorf("event = %+v, want in_transit at Toronto, CANADA", got)
+		}
+	}
+
+	if fake.tokens != 1 {
+		t.Errorf("got %d token requests, want 1", fake.tokens)
+	}
+	if want := []string{shipment.TrackingNumber, shipment.TrackingNumber}; !slices.Equal(fake.tracked, want) {
+		t.Errorf("tracked %v, want %v", fake.tracked, want)
+	}
+}
+
+func TestProcessNotYetInSystemIsPending(t *testing.T) {
+	fake := &fakeUSPS{
+		t: t,
+		response: ApiResponse{
+			TrackingNumber: shipment.TrackingNumber,
+			Status:         "Label Created, not yet in system",
+			StatusCategory: "Pre-Shipment",
+		},
+	}
+
+	result, err := newTestProcessor(t, fake, newTestStore()).Process(context.Background(), shipment)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if result.Status != "pending" {
+		t.Errorf("Status = %q, want pending", result.Status)
+	}
+	if len(result.Events) != 0 {
+		t.Errorf("got %d events, want none", len(result.Events))
+	}
+}
+
+func TestProcessFallsBackToStatusCategory(t *testing.T) {
+	fake := &fakeUSPS{
+		t: t,
+		response: ApiResponse{
+			TrackingNumber: shipment.TrackingNumber,
+			StatusCategory: "Available for Pickup",
+			TrackingEvents: []TrackingEvent{{
+				EventType:      "Available for Pickup",
+				EventCode:      "ZZ",
+				EventTimestamp: "2024-03-05T08:00:00",
+				EventCity:      "BOISE",
+				EventState:     "ID",
+			}},
+		},
+	}
+	store := newTestStore()
+	p := newTestProcessor(t, fake, store)
+
+	result, err := p.Process(context.Background(), shipment)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if result.Status != "awaiting_pickup" {
+		t.Errorf("Status = %q, want awaiting_pickup", result.Status)
+	}
+
+	p.statuses.Flush()
+	recorded := store.Recorded()
+	if len(recorded) != 1 || recorded[0].Carrier != "usps" || recorded[0].Code != "ZZ" {
+		t.Errorf("recorded %v, want the unmapped usps code ZZ", recorded)
+	}
+}
+
+func TestGetExpectedDeliveryWindow(t *testing.T) {
+	at := func(day int, hour int, minute int, second int) time.Time {
+		return time.Date(2024, time.March, day, hour, minute, second, 0, time.Local)
+	}
+
+	tests := []struct {
+		name      string
+		details   ApiResponse
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{
+			name:      "predicted window",
+			details:   ApiResponse{PredictedDeliveryDate: "2024-03-05T00:00:00", PredictedDeliveryWindowStartTime: "09:00:00", PredictedDeliveryWindowEndTime: "13:00:00"},
+			wantStart: at(5, 9, 0, 0),
+			wantEnd:   at(5, 13, 0, 0),
+		},
+		{
+			name:      "predicted date covers the day",
+			details:   ApiResponse{PredictedDeliveryDate: "2024-03-05", ExpectedDeliveryTimeStamp: "2024-03-07T20:00:00"},
+			wantStart: at(5, 0, 0, 0),
+			wantEnd:   at(5, 23, 59, 59),
+		},
+		{
+			name:    "expected timestamp",
+			details: ApiResponse{ExpectedDeliveryTimeStamp: "2024-03-06T20:00:00", GuaranteedDeliveryTimeStamp: "2024-03-07T12:00:00"},
+			wantEnd: at(6, 20, 0, 0),
+		},
+		{
+			name:    "expected date is end of day",
+			details: ApiResponse{ExpectedDeliveryTimeStamp: "2024-03-06"},
+			wantEnd: at(6, 23, 59, 59),
+		},
+		{
+			name:    "guaranteed timestamp",
+			details: ApiResponse{GuaranteedDeliveryTimeStamp: "2024-03-07T12:00:00"},
+			wantEnd: at(7, 12, 0, 0),
+		},
+		{
+			name:    "nothing known",
+			details: ApiResponse{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := getExpectedDeliveryWindow(&tt.details)
+			if err != nil {
+				t.Fatalf("getExpectedDeliveryWindow() error = %v", err)
+			}
+			assertTime(t, "start", start, tt.wantStart)
+			assertTime(t, "end", end, tt.wantEnd)
+		})
+	}
+}
+
+func assertTime(t *testing.T, name string, got *time.Time, want time.Time) {
+	t.Helper()
+
+	if want.IsZero() {
+		if got != nil {
+			t.Errorf("%s = %s, want nil", name, got)
+		}
+		return
+	}
+
+	if got == nil || !got.Equal(want) {
+		t.Errorf("%s = %v, want %s", name, got, want)
+	}
+}
+
+func TestFormatLocation(t *testing.T) {
+	tests := []struct {
+		name  string
+		event TrackingEvent
+		want  string
+	}{
+		{"us city", TrackingEvent{EventCity: "SALT LAKE CITY", EventState: "UT", EventCountry: "US"}, "Salt Lake City, UT"},
+		{"united states spelled out", TrackingEvent{EventCity: "BOISE", EventState: "ID", EventCountry: "UNITED STATES"}, "Boise, ID"},
+		{"no country", TrackingEvent{EventCity: "BOISE", EventState: "ID"}, "Boise, ID"},
+		{"international", TrackingEvent{EventCity: "TORONTO", EventState: "ON", EventCountry: "CANADA"}, "Toronto, CANADA"},
+		{"region only", TrackingEvent{EventState: "UT"}, "UT"},
+		{"city only", TrackingEvent{EventCity: "ANCHORAGE"}, "Anchorage"},
+		{"nothing known", TrackingEvent{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatLocation(tt.event); got != tt.want {
+				t.Errorf("formatLocation() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package usps
+
+type OAuthRequest struct {
+	GrantType    string `json:"grant_type"`
+	ClientId     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type OAuthResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+type TrackingEvent struct {
+	EventType      string `json:"eventType"`
+	EventTimestamp string `json:"eventTimestamp"`
+	GMTTimestamp   string `json:"GMTTimestamp"`
+	GMTOffset      string `json:"GMTOffset"`
+	EventCountry   string `json:"eventCountry"`
+	EventCity      string `json:"eventCity"`
+	EventState     string `json:"eventState"`
+	EventZIP       string `json:"eventZIP"`
+	EventCode      string `json:"eventCode"`
+}
+
+type ApiResponse struct {
+	TrackingNumber                   string          `json:"trackingNumber"`
+	Status                           string          `json:"status"`
+	StatusCategory                   string          `json:"statusCategory"`
+	StatusSummary                    string          `json:"statusSummary"`
+	ExpectedDeliveryTimeStamp        string          `json:"expectedDeliveryTimeStamp"`
+	GuaranteedDeliveryTimeStamp      string          `json:"guaranteedDeliveryTimeStamp"`
+	PredictedDeliveryDate            string          `json:"predictedDeliveryDate"`
+	PredictedDeliveryWindowStartTime string          `json:"predictedDeliveryWindowStartTime"`
+	PredictedDeliveryWindowEndTime   string          `json:"predictedDeliveryWindowEndTime"`
+	TrackingEvents                   []TrackingEvent `json:"trackingEvents"`
+}
@@ -2,7 +2,9 @@ package repositories
 
 import (
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"personal-homepage-service/workers/shipments/models"
+	"time"
 )
 
 type Repository struct {
@@ -29,12 +31,137 @@ func (r *Repository) GetOpenShipments() ([]models.Shipment, error) {
 	return shipments, err
 }
 
+// GetShipmentsDeliveredSince returns shipments found delivered at or after
+// since, using the check that saw the delivery as its time.
+func (r *Repository) GetShipmentsDeliveredSince(since time.Time) ([]models.Shipment, error) {
+	var shipments []models.Shipment
+	err := r.db.Joins("Status").
+		Preload("Status").
+		Preload("Carrier").
+		Where("\"Status\".key = ? AND shipments.last_checked_at >= ?", "delivered", since).
+		Order("shipments.last_checked_at").
+		Find(&shipments).Error
+	return shipments, err
+}
+
+func (r *Repository) GetShipment(id uint) (models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.db.Preload("Status").Preload("Carrier").First(&shipment, id).Error
+	return shipment, err
+}
+
+func (r *Repository) GetShipmentByTrackingNumber(trackingNumber string) (models.Shipment, error) {
+	var shipment models.Shipment
+	err := r.db.Preload("Status").Preload("Carrier").
+		Where("tracking_number = ?", trackingNumber).
+		First(&shipment).Error
+	return shipment, err
+}
+
 func (r *Repository) GetStatus(key string) (models.ShipmentStatus, error) {
 	var status models.ShipmentStatus
 	err := r.db.Where("key = ?", key).First(&status).Error
 	return status, err
 }
 
+func (r *Repository) GetCarrier(key string) (models.ShipmentCarrier, error) {
+	var carrier models.ShipmentCarrier
+	err := r.db.Where("key = ?", key).First(&carrier).Error
+	return carrier, err
+}
+
+func (r *Repository) GetScrapeDefinitions() ([]models.CarrierScrapeDefinition, error) {
+	var definitions []models.CarrierScrapeDefinition
+	err := r.db.Preload("Carrier").Find(&definitions).Error
+	return definitions, err
+}
+
+// GetStatusMappings returns the mapped status key of every raw code known for
+// a carrier.
+func (r *Repository) GetStatusMappings(carrier string) (map[string]string, error) {
+	var rows []models.CarrierStatusMapping
+	err := r.db.Joins("Carrier").
+		Where("\"Carrier\".key = ? AND carrier_status_mappings.status_key IS NOT NULL", carrier).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	mappings := make(map[string]string, len(rows))
+	for _, row := range rows {
+		mappings[row.Code] = *row.StatusKey
+	}
+	return mappings, nil
+}
+
+// RecordUnmappedStatus adds a code with no status key for the carrier, or
+// adds seen to how often it was seen and updates when it was last seen.
+func (r *Repository) RecordUnmappedStatus(carrier string, code string, description string, seen int) error {
+	return r.db.Exec(`INSERT INTO carrier_status_mappings
+			(carrier_id, code, description, first_seen_at, last_seen_at, occurrences)
+		SELECT id, ?, ?, now(), now(), ? FROM shipment_carriers WHERE key = ?
+		ON CONFLICT (carrier_id, code) DO UPDATE SET
+			last_seen_at = now(),
+			occurrences = carrier_status_mappings.occurrences + EXCLUDED.occurrences`,
+		code, truncate(description, 256), seen, carrier,
+	).Error
+}
+
+// GetUnmappedStatuses returns codes carriers have sent that have no status
+// key, most recently seen first.
+func (r *Repository) GetUnmappedStatuses() ([]models.CarrierStatusMapping, error) {
+	var rows []models.CarrierStatusMapping
+	err := r.db.Preload("Carrier").
+		Where("status_key IS NULL").
+		Order("last_seen_at desc").
+		Find(&rows).Error
+	return rows, err
+}
+
 func (r *Repository) SaveShipment(shipment *models.Shipment) error {
 	return r.db.Save(shipment).Error
 }
+
+// SaveShipmentWithEvents saves the shipment and inserts any events not
+// already recorded for it, in one transaction. afterSave, when given, runs in
+// the same transaction and rolls everything back if it fails.
+func (r *Repository) SaveShipmentWithEvents(shipment *models.Shipment, events []models.ShipmentEvent, afterSave func(tx *gorm.DB) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(shipment).Error; err != nil {
+			return err
+		}
+
+		if len(events) > 0 {
+			for i := range events {
+				events[i].ShipmentID = shipment.ID
+			}
+
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&events).Error; err != nil {
+				return err
+			}
+		}
+
+		if afterSave == nil {
+			return nil
+		}
+		return afterSave(tx)
+	})
+}
+
+// GetShipmentEvents returns a shipment's timeline, newest first.
+func (r *Repository) GetShipmentEvents(shipmentID uint) ([]models.ShipmentEvent, error) {
+	var events []models.ShipmentEvent
+	err := r.db.Where("shipment_id = ?", shipmentID).
+		Order("occurred_at desc").
+		Find(&events).Error
+	return events, err
+}
+
+// truncate shortens s to at most n characters.
+func truncate(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return string(runes[:n])
+}
@@ -0,0 +1,104 @@
+package shipments
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+	"personal-homepage-service/core"
+	"personal-homepage-service/workers/shipments/models"
+	"personal-homepage-service/workers/shipments/processors"
+	"time"
+)
+
+// RateLimitedError is returned for a shipment whose carrier has no request to
+// spare within the configured wait, such as when a daily quota is used up.
+type RateLimitedError struct {
+	Until time.Time
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("carrier rate limit reached until %s", e.Until.Format(time.RFC3339))
+}
+
+func (e *RateLimitedError) Unwrap() error {
+	return processors.ErrRateLimited
+}
+
+// processWithRetry runs the carrier processor for a shipment, retrying
+// transient failures with jittered exponential backoff. Every attempt counts
+// against the carrier's rate limit, but the attempts together count as a
+// single call to the carrier's circuit breaker.
+func (w *Worker) processWithRetry(ctx context.Context, sh models.Shipment) (*processors.CarrierTrackingResults, error) {
+	breaker, err := w.getProcessor(carrierKey(sh))
+	if err != nil {
+		return nil, err
+	}
+
+	return breaker.Call(func(processor processors.CarrierTrackingProcessor) (*processors.CarrierTrackingResults, error) {
+		return w.retry(ctx, processor, sh)
+	})
+}
+
+func (w *Worker) retry(ctx context.Context, processor processors.CarrierTrackingProcessor, sh models.Shipment) (*processors.CarrierTrackingResults, error) {
+	limiter := w.getLimiter(carrierKey(sh))
+	attempts := max(w.cfg.Shipments.RetryAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		if err := w.reserve(ctx, limiter); err != nil {
+			return nil, err
+		}
+
+		result, err := processor.Process(ctx, sh)
+		if err == nil || attempt >= attempts || !processors.IsRetryable(err) {
+			return result, err
+		}
+
+		delay := core.Backoff(attempt, w.cfg.Shipments.RetryBaseDelay, w.cfg.Shipments.RetryMaxDelay)
+		w.logger.Warn("Transient carrier error, retrying",
+			zap.String("tracking_number", sh.TrackingNumber),
+			zap.Int("attempt", attempt),
+			zap.Duration("delay", delay),
+			zap.Error(err),
+		)
+		core.CountersFromContext(ctx).Add("shipments_retried", 1)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// reserve takes a request from the carrier's rate limiter. It only waits when
+// the request is available within MaxRateWait; carriers with hourly or daily
+// quotas can be hours away from their next request, and waiting for them
+// would hold a worker for the whole run.
+func (w *Worker) reserve(ctx context.Context, limiter *rate.Limiter) error {
+	now := time.Now()
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return &RateLimitedError{Until: now}
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay > w.cfg.Shipments.MaxRateWait {
+		reservation.CancelAt(now)
+		return &RateLimitedError{Until: now.Add(delay)}
+	}
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		reservation.Cancel()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
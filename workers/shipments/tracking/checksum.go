@@ -0,0 +1,92 @@
+package tracking
+
+// validUPS verifies the final character of a 1Z number. Letters map to digits
+// starting from A = 2, and every second character is doubled.
+func validUPS(number string) bool {
+	body := number[2:17]
+	sum := 0
+	for i, c := range body {
+		var v int
+		if c >= '0' && c <= '9' {
+			v = int(c - '0')
+		} else {
+			v = (int(c-'A') + 2) % 10
+		}
+		if i%2 == 1 {
+			v *= 2
+		}
+		sum += v
+	}
+	return checkDigit(number[17]) == (10-sum%10)%10
+}
+
+// validUSPS verifies an IMpb barcode, ignoring any routing prefix.
+func validUSPS(number string) bool {
+	return validMod10(stripUSPSRouting(number))
+}
+
+// validS10 verifies a UPU S10 item identifier such as EC123456785US.
+func validS10(number string) bool {
+	weights := []int{8, 6, 4, 2, 3, 5, 9, 7}
+	sum := 0
+	for i, w := range weights {
+		sum += int(number[2+i]-'0') * w
+	}
+
+	check := 11 - sum%11
+	switch check {
+	case 10:
+		check = 0
+	case 11:
+		check = 5
+	}
+	return checkDigit(number[10]) == check
+}
+
+// validFedExGround verifies the trailing 15 digits, which is all that the
+// 22 digit SSCC form adds to.
+func validFedExGround(number string) bool {
+	return validMod10(number[len(number)-15:])
+}
+
+// validFedExExpress applies weights 1, 3, 7 from the right to the first 11
+// digits, modulo 11.
+func validFedExExpress(number string) bool {
+	weights := []int{1, 3, 7}
+	sum := 0
+	for i := 0; i < 11; i++ {
+		sum += int(number[10-i]-'0') * weights[i%3]
+	}
+	return checkDigit(number[11]) == sum%11%10
+}
+
+// validDHLExpress checks that the last digit is the first nine modulo 7.
+func validDHLExpress(number string) bool {
+	n := 0
+	for i := 0; i < 9; i++ {
+		n = n*10 + int(number[i]-'0')
+	}
+	return checkDigit(number[9]) == n%7
+}
+
+// validMod10 applies alternating weights 3 and 1, starting from the digit
+// next to the check digit.
+func validMod10(number string) bool {
+	last := len(number) - 1
+	sum := 0
+	for i := 0; i < last; i++ {
+		weight := 1
+		if i%2 == 0 {
+			weight = 3
+		}
+		sum += int(number[last-1-i]-'0') * weight
+	}
+	return checkDigit(number[last]) == (10-sum%10)%10
+}
+
+func checkDigit(c byte) int {
+	if c < '0' || c > '9' {
+		return -1
+	}
+	return int(c - '0')
+}
@@ -0,0 +1,182 @@
+package tracking
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Match is a carrier whose tracking number format, and checksum where the
+// format has one, fits a tracking number.
+type Match struct {
+	Carrier     string
+	Number      string
+	TrackingURL string
+	// Checksummed is true when the format carries a check digit and it
+	// verified, which makes the match far more trustworthy.
+	Checksummed bool
+}
+
+type format struct {
+	carrier     string
+	pattern     *regexp.Regexp
+	checksum    func(number string) bool
+	canonical   func(number string) string
+	urlTemplate string
+}
+
+// formats are ordered from most to least specific; Detect keeps that order.
+var formats = []format{
+	{
+		carrier:     "ups",
+		pattern:     regexp.MustCompile(`^1Z[0-9A-Z]{16}$`),
+		checksum:    validUPS,
+		urlTemplate: "https://www.ups.com/track?tracknum=%s",
+	},
+	{
+		// Intelligent Mail package barcodes, optionally preceded by the
+		// 420 + ZIP routing prefix printed on the label
+		carrier:     "usps",
+		pattern:     regexp.MustCompile(`^(420\d{5}(\d{4})?)?9[1-5]\d{18,20}$`),
+		checksum:    validUSPS,
+		canonical:   stripUSPSRouting,
+		urlTemplate: "https://tools.usps.com/go/TrackConfirmAction?tLabels=%s",
+	},
+	{
+		// UPU S10 international items handed to USPS
+		carrier:     "usps",
+		pattern:     regexp.MustCompile(`^[A-Z]{2}\d{9}US$`),
+		checksum:    validS10,
+		urlTemplate: "https://tools.usps.com/go/TrackConfirmAction?tLabels=%s",
+	},
+	{
+		carrier:     "amazon",
+		pattern:     regexp.MustCompile(`^TBA\d{12}$`),
+		urlTemplate: "https://track.amazon.com/tracking/%s",
+	},
+	{
+		carrier:     "ontrac",
+		pattern:     regexp.MustCompile(`^[CD]\d{14}$`),
+		urlTemplate: "https://www.ontrac.com/tracking/?number=%s",
+	},
+	{
+		carrier: "uds",
+		pattern: regexp.MustCompile(`^UDS[0-9A-Z]{6,}$`),
+		// UDS tracking pages are linked from the retailer's shipping email,
+		// so there is no canonical URL to build.
+	},
+	{
+		// DHL eCommerce
+		carrier:     "dhl",
+		pattern:     regexp.MustCompile(`^(GM\d{16,18}|JJD\d{18,20}|JVGL\d{16,20})$`),
+		urlTemplate: "https://www.dhl.com/us-en/home/tracking/tracking-ecommerce.html?tracking-id=%s",
+	},
+	{
+		// FedEx Ground, including the 96-prefixed SSCC barcode
+		carrier:     "fedex",
+		pattern:     regexp.MustCompile(`^(96\d{5})?\d{15}$`),
+		checksum:    validFedExGround,
+		urlTemplate: "https://www.fedex.com/fedextrack/?trknbr=%s",
+	},
+	{
+		// FedEx Express
+		carrier:     "fedex",
+		pattern:     regexp.MustCompile(`^\d{12}$`),
+		checksum:    validFedExExpress,
+		urlTemplate: "https://www.fedex.com/fedextrack/?trknbr=%s",
+	},
+	{
+		// DHL Express waybills
+		carrier:     "dhl",
+		pattern:     regexp.MustCompile(`^\d{10}$`),
+		checksum:    validDHLExpress,
+		urlTemplate: "https://www.dhl.com/us-en/home/tracking/tracking-express.html?tracking-id=%s",
+	},
+}
+
+var separators = strings.NewReplacer(" ", "", "-", "", "\t", "")
+
+// Normalize strips the spacing and dashes labels are printed with and
+// upper-cases the result.
+func Normalize(number string) string {
+	return strings.ToUpper(separators.Replace(strings.TrimSpace(number)))
+}
+
+// Detect returns every carrier whose format matches the tracking number,
+// checksummed matches first. Formats with a check digit only match when it
+// verifies.
+func Detect(number string) []Match {
+	normalized := Normalize(number)
+
+	var checked, unchecked []Match
+	for _, f := range formats {
+		if !f.pattern.MatchString(normalized) {
+			continue
+		}
+		if f.checksum != nil && !f.checksum(normalized) {
+			continue
+		}
+
+		canonical := normalized
+		if f.canonical != nil {
+			canonical = f.canonical(normalized)
+		}
+
+		match := Match{
+			Carrier:     f.carrier,
+			Number:      canonical,
+			TrackingURL: buildURL(f.urlTemplate, canonical),
+			Checksummed: f.checksum != nil,
+		}
+
+		if match.Checksummed {
+			checked = append(checked, match)
+		} else {
+			unchecked = append(unchecked, match)
+		}
+	}
+
+	return append(checked, unchecked...)
+}
+
+// Suggest returns the carrier a tracking number most likely belongs to. It
+// declines when the number matches several carriers equally well.
+func Suggest(number string) (Match, bool) {
+	matches := Detect(number)
+	if len(matches) == 0 {
+		return Match{}, false
+	}
+
+	best := matches[0]
+	for _, m := range matches[1:] {
+		if m.Checksummed == best.Checksummed && m.Carrier != best.Carrier {
+			return Match{}, false
+		}
+	}
+
+	return best, true
+}
+
+// stripUSPSRouting drops the 420 + ZIP or ZIP+4 routing prefix that precedes
+// the tracking number in the printed barcode.
+func stripUSPSRouting(number string) string {
+	if !strings.HasPrefix(number, "420") {
+		return number
+	}
+
+	for _, prefix := range []int{12, 8} {
+		rest := number[min(prefix, len(number)):]
+		if len(rest) >= 20 && len(rest) <= 22 && rest[0] == '9' && rest[1] >= '1' && rest[1] <= '5' {
+			return rest
+		}
+	}
+	return number
+}
+
+func buildURL(template string, number string) string {
+	if template == "" {
+		return ""
+	}
+	return fmt.Sprintf(template, url.QueryEscape(number))
+}
@@ -0,0 +1,86 @@
+package tracking
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name        string
+		number      string
+		carrier     string
+		canonical   string
+		checksummed bool
+	}{
+		{"ups", "1Z999AA10123456784", "ups", "1Z999AA10123456784", true},
+		{"ups lower case with spaces", "1z 999 aa1 01 2345 6784", "ups", "1Z999AA10123456784", true},
+		{"usps impb", "9205590164917312751089", "usps", "9205590164917312751089", true},
+		{"usps impb with dashes", "9205-5901-6491-7312-7510-89", "usps", "9205590164917312751089", true},
+		{"usps with 420 zip prefix", "420221539101026837331000039521", "usps", "9101026837331000039521", true},
+		{"usps with 420 zip+4 prefix", "4202215312349205590164917312751089", "usps", "9205590164917312751089", true},
+		{"usps s10", "EC123456785US", "usps", "EC123456785US", true},
+		{"fedex express", "794843185271", "fedex", "794843185271", true},
+		{"fedex ground", "449044304137821", "fedex", "449044304137821", true},
+		{"fedex ground sscc", "9612019449044304137821", "fedex", "9612019449044304137821", true},
+		{"dhl express", "1234567891", "dhl", "1234567891", true},
+		{"dhl ecommerce", "GM2951173225174494", "dhl", "GM2951173225174494", false},
+		{"amazon", "TBA123456789012", "amazon", "TBA123456789012", false},
+		{"ontrac", "C12345678901234", "ontrac", "C12345678901234", false},
+		{"uds", "UDS12345678", "uds", "UDS12345678", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches := Detect(tt.number)
+			if len(matches) != 1 {
+				t.Fatalf("Detect(%q) = %+v, want one %s match", tt.number, matches, tt.carrier)
+			}
+
+			m := matches[0]
+			if m.Carrier != tt.carrier || m.Number != tt.canonical || m.Checksummed != tt.checksummed {
+				t.Errorf("Detect(%q) = %s %s checksummed=%t, want %s %s checksummed=%t",
+					tt.number, m.Carrier, m.Number, m.Checksummed, tt.carrier, tt.canonical, tt.checksummed)
+			}
+		})
+	}
+}
+
+func TestDetectRejectsBadCheckDigits(t *testing.T) {
+	tests := []struct {
+		name   string
+		number string
+	}{
+		{"ups", "1Z999AA10123456785"},
+		{"usps impb", "9205590164917312751088"},
+		{"usps with 420 zip prefix", "420221539101026837331000039522"},
+		{"usps s10", "EC123456786US"},
+		{"fedex express", "794843185272"},
+		{"fedex ground", "449044304137822"},
+		{"fedex ground sscc", "9612019449044304137822"},
+		{"dhl express", "1234567892"},
+		{"too short", "92055901649173127510"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if matches := Detect(tt.number); len(matches) != 0 {
+				t.Errorf("Detect(%q) = %+v, want no matches", tt.number, matches)
+			}
+		})
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	m, ok := Suggest("420 22153 9101 0268 3733 1000 0395 21")
+	if !ok {
+		t.Fatal("Suggest() found no carrier")
+	}
+	if m.Carrier != "usps" || m.Number != "9101026837331000039521" {
+		t.Errorf("Suggest() = %s %s, want usps 9101026837331000039521", m.Carrier, m.Number)
+	}
+	if want := "https://tools.usps.com/go/TrackConfirmAction?tLabels=9101026837331000039521"; m.TrackingURL != want {
+		t.Errorf("TrackingURL = %q, want %q", m.TrackingURL, want)
+	}
+
+	if m, ok := Suggest("not a tracking number"); ok {
+		t.Errorf("Suggest() = %+v, want no match", m)
+	}
+}
@@ -1,81 +1,191 @@
 package shipments
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 	"gorm.io/gorm"
-	"log"
+	"personal-homepage-service/config"
+	"personal-homepage-service/core"
+	"personal-homepage-service/metrics"
 	"personal-homepage-service/workers/shipments/models"
 	"personal-homepage-service/workers/shipments/processors"
-	"personal-homepage-service/workers/shipments/processors/uds"
-	"personal-homepage-service/workers/shipments/processors/unsupported"
-	"personal-homepage-service/workers/shipments/processors/ups"
 	"personal-homepage-service/workers/shipments/repositories"
+	"personal-homepage-service/workers/shipments/tracking"
+	"reflect"
+	"slices"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// scrapeDefinitionsTTL is how long carrier scrape definitions are cached, and
+// so how long an edited definition takes to be picked up without a restart.
+const scrapeDefinitionsTTL = 5 * time.Minute
+
 type Worker struct {
 	logger     *zap.Logger
+	cfg        *config.Config
+	registry   *processors.Registry
+	statuses   *processors.StatusMapper
+	events     *core.EventBus
 	repo       *repositories.Repository
-	processors map[string]processors.CarrierTrackingProcessor
+	processors map[string]*processors.CircuitBreaker
+	limiters   map[string]*rate.Limiter
 	mu         sync.Mutex
-	busy       bool
+	busy       atomic.Bool
+
+	// refreshMu serializes reloading scrape definitions, so the database is
+	// never queried while mu is held.
+	refreshMu   sync.Mutex
+	definitions []models.CarrierScrapeDefinition
+	loadedAt    time.Time
 }
 
-func NewWorker(logger *zap.Logger, db *gorm.DB) *Worker {
+func NewWorker(logger *zap.Logger, cfg config.Config, db *gorm.DB, events *core.EventBus) *Worker {
 	repo := repositories.NewRepository(db)
+
 	return &Worker{
 		logger:     logger,
+		cfg:        &cfg,
+		statuses:   processors.NewStatusMapper(repo, logger),
+		events:     events,
 		repo:       repo,
-		processors: make(map[string]processors.CarrierTrackingProcessor),
+		processors: make(map[string]*processors.CircuitBreaker),
+		limiters:   make(map[string]*rate.Limiter),
 	}
 }
+func (w *Worker) Name() string {
+	return "shipments"
+}
 
 func (w *Worker) Schedule() string {
 	return "*/5 * * * *"
 }
 
 func (w *Worker) Ready(time.Time) bool {
-	return !w.busy
+	return !w.busy.Load()
 }
 
-func (w *Worker) Execute() {
-	w.busy = true
-	defer func() {
-		w.busy = false
-	}()
+func (w *Worker) Execute(ctx context.Context) error {
+	if !w.busy.CompareAndSwap(false, true) {
+		return core.ErrWorkerBusy
+	}
+	defer w.busy.Store(false)
+	defer w.statuses.Flush()
+
+	if err := w.refreshRegistry(); err != nil {
+		return err
+	}
 
 	shipments, err := w.repo.GetOpenShipments()
 	if err != nil {
-		log.Fatal(err)
-		return
+		return fmt.Errorf("failed to load open shipments: %w", err)
 	}
 
+	w.reportOpenShipments(shipments)
+
 	if len(shipments) == 0 {
 		w.logger.Info("No active shipments found. Shipment work skipped 😴")
-		return
+		return nil
 	}
 
 	shipmentsToProcess := w.getShipmentsToProcess(shipments)
 
 	if len(shipmentsToProcess) == 0 {
 		w.logger.Info("No shipments are ready to be processed. Shipment work skipped 😴")
-		return
+		return nil
 	}
 
 	w.logger.Info("Starting shipment processing.")
 
+	queue := make(chan models.Shipment)
+	poolSize := min(max(w.cfg.Shipments.Concurrency, 1), len(shipmentsToProcess))
+
 	var wg sync.WaitGroup
-	for _, shipment := range shipmentsToProcess {
+	for range poolSize {
 		wg.Add(1)
-		go func(sh models.Shipment) {
+		go func() {
 			defer wg.Done()
-			w.processShipment(sh)
-		}(shipment)
+			for sh := range queue {
+				_ = w.processShipment(ctx, sh)
+			}
+		}()
 	}
 
+	for _, shipment := range shipmentsToProcess {
+		queue <- shipment
+	}
+	close(queue)
+
 	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		w.logger.Warn("Shipment work interrupted", zap.Error(err))
+		return err
+	}
+
 	w.logger.Info("Shipment work completed 😴")
+	return nil
+}
+
+// ExecuteTarget processes a single shipment by ID, bypassing the polling
+// frequency checks. Unlike a scheduled run, a shipment skipped because its
+// carrier is unavailable is reported as an error, such as ErrCircuitOpen.
+func (w *Worker) ExecuteTarget(ctx context.Context, target string) error {
+	if !w.busy.CompareAndSwap(false, true) {
+		return core.ErrWorkerBusy
+	}
+	defer w.busy.Store(false)
+	defer w.statuses.Flush()
+
+	if err := w.refreshRegistry(); err != nil {
+		return err
+	}
+
+	id, err := strconv.ParseUint(target, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid shipment id %q: %w", target, err)
+	}
+
+	shipment, err := w.repo.GetShipment(uint(id))
+	if err != nil {
+		return fmt.Errorf("failed to load shipment %d: %w", id, err)
+	}
+
+	return w.processShipment(ctx, shipment)
+}
+
+// Check runs the carrier processor for a shipment and returns the results
+// without saving them.
+func (w *Worker) Check(ctx context.Context, sh models.Shipment) (*processors.CarrierTrackingResults, error) {
+	defer w.statuses.Flush()
+
+	if err := w.refreshRegistry(); err != nil {
+		return nil, err
+	}
+
+	processor, err := w.getProcessor(carrierKey(sh))
+	if err != nil {
+		return nil, err
+	}
+
+	return processor.Process(ctx, sh)
+}
+
+func (w *Worker) reportOpenShipments(ss []models.Shipment) {
+	counts := make(map[string]int)
+	for _, s := range ss {
+		key := "none"
+		if s.Status != nil {
+			key = s.Status.Key
+		}
+		counts[key]++
+	}
+	metrics.SetOpenShipments(counts)
 }
 
 func (w *Worker) getShipmentsToProcess(ss []models.Shipment) (ret []models.Shipment) {
@@ -98,11 +208,22 @@ func (w *Worker) shouldCheck(shipment models.Shipment) bool {
 		return false
 	}
 
+	now := time.Now()
+
+	// Shipments that have been failing, or were deferred by a carrier's rate
+	// limit, wait for their next check instead of following the regular
+	// polling cadence.
+	if shipment.NextCheckAt != nil && now.Before(*shipment.NextCheckAt) {
+		return false
+	}
+	if shipment.ConsecutiveFailures > 0 {
+		return true
+	}
+
 	if shipment.Status.Key == "unchecked" || shipment.LastCheckedAt == nil {
 		return true
 	}
 
-	now := time.Now()
 	timeSinceLastCheck := now.Sub(*shipment.LastCheckedAt)
 
 	if timeSinceLastCheck > frequencyThreshold {
@@ -117,16 +238,96 @@ func (w *Worker) shouldCheck(shipment models.Shipment) bool {
 
 	return timeUntilExpected < soonThreshold && timeSinceLastCheck > recheckDelay
 }
-func (w *Worker) processShipment(sh models.Shipment) {
-	processor := w.getProcessor(sh.Carrier.Key)
 
-	result, err := processor.Process(sh)
+// runCancelled reports whether err is the run's own context being cancelled or
+// running out of time, as opposed to a carrier request timing out.
+func runCancelled(ctx context.Context, err error) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// processShipment checks a shipment with its carrier and saves the outcome.
+// Shipments skipped because the carrier is unavailable return the reason so a
+// targeted run can report it, but are not counted as failed.
+func (w *Worker) processShipment(ctx context.Context, sh models.Shipment) (err error) {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	counters := core.CountersFromContext(ctx)
+	counters.Add("shipments_checked", 1)
+
+	skipped := false
+
+	defer func() {
+		if r := recover(); r != nil {
+			w.logger.Error("Shipment processing panicked",
+				zap.String("tracking_number", sh.TrackingNumber),
+				zap.Any("panic", r),
+				zap.Stack("stack"),
+			)
+			err = fmt.Errorf("panic: %v", r)
+		}
+
+		// A run cut short by shutdown or the drain timeout is not the
+		// shipment's failure.
+		if err != nil && !skipped && !runCancelled(ctx, err) {
+			counters.Add("shipments_failed", 1)
+		}
+	}()
+
+	if sh.Carrier == nil {
+		w.assignCarrier(&sh)
+	}
+
+	result, err := w.processWithRetry(ctx, sh)
+	if errors.Is(err, processors.ErrCircuitOpen) || errors.Is(err, processors.ErrCarrierNotConfigured) {
+		w.logger.Info("Carrier unavailable, shipment skipped",
+			zap.String("tracking_number", sh.TrackingNumber),
+			zap.String("carrier", carrierKey(sh)),
+			zap.Error(err),
+		)
+		counters.Add("shipments_skipped", 1)
+		skipped = true
+		return err
+	}
+	// A carrier out of quota is not the shipment's fault either: it waits
+	// until the carrier has requests to spare.
+	var limited *RateLimitedError
+	if errors.As(err, &limited) {
+		w.deferShipment(ctx, &sh, limited.Until)
+		counters.Add("shipments_skipped", 1)
+		skipped = true
+		return err
+	}
+	// Rejected credentials are our problem, not the shipment's: it is skipped
+	// without counting a failure, and the breaker opens if it keeps happening.
+	if processors.IsAuth(err) {
+		w.logger.Error("Carrier rejected credentials, shipment skipped",
+			zap.String("tracking_number", sh.TrackingNumber),
+			zap.String("carrier", carrierKey(sh)),
+			zap.Error(err),
+		)
+		counters.Add("shipments_skipped", 1)
+		skipped = true
+		return err
+	}
 	if err != nil {
 		w.logger.Error("Failed to process shipment",
 			zap.String("tracking_number", sh.TrackingNumber),
 			zap.Error(err),
 		)
-		return
+
+		if ctx.Err() == nil {
+			if processors.IsPermanent(err) {
+				w.markInvalid(ctx, &sh, err)
+			} else {
+				w.recordFailure(ctx, &sh, err)
+			}
+		}
+		return err
 	}
 
 	status, err := w.repo.GetStatus(result.Status)
@@ -136,27 +337,216 @@ func (w *Worker) processShipment(sh models.Shipment) {
 			zap.String("status_key", result.Status),
 			zap.Error(err),
 		)
-		return
+		return err
+	}
+
+	// Skip the save once shutdown has begun so a cancelled run never persists
+	// partially gathered results.
+	if err := ctx.Err(); err != nil {
+		return err
 	}
 
+	previous := sh
 	w.updateShipmentFromResult(&sh, result, &status)
 
-	if err := w.repo.SaveShipment(&sh); err != nil {
+	if err := w.saveShipment(ctx, previous, &sh, toShipmentEvents(result.Events)); err != nil {
 		w.logger.Error("Failed to save shipment",
 			zap.String("tracking_number", sh.TrackingNumber),
 			zap.Error(err),
 		)
-		return
+		return err
 	}
 
+	counters.Add("shipments_updated", 1)
+
 	w.logger.Info("Shipment successfully processed",
 		zap.String("tracking_number", sh.TrackingNumber),
 	)
+	return nil
+}
+
+// assignCarrier detects the carrier of a shipment that has none from its
+// tracking number, replacing the number with the carrier's canonical form and
+// filling in the tracking URL if it is missing.
+func (w *Worker) assignCarrier(sh *models.Shipment) {
+	match, ok := tracking.Suggest(sh.TrackingNumber)
+	if !ok {
+		w.logger.Info("Could not detect shipment carrier",
+			zap.String("tracking_number", sh.TrackingNumber),
+		)
+		return
+	}
+
+	carrier, err := w.repo.GetCarrier(match.Carrier)
+	if err != nil {
+		w.logger.Error("Failed to get shipment carrier",
+			zap.String("tracking_number", sh.TrackingNumber),
+			zap.String("carrier_key", match.Carrier),
+			zap.Error(err),
+		)
+		return
+	}
+
+	number := sh.TrackingNumber
+	sh.Carrier = &carrier
+	sh.CarrierID = &carrier.ID
+	sh.TrackingNumber = match.Number
+	if sh.TrackingURL == "" {
+		sh.TrackingURL = match.TrackingURL
+	}
+
+	if err := w.repo.SaveShipment(sh); err != nil {
+		w.logger.Error("Failed to save shipment",
+			zap.String("tracking_number", number),
+			zap.Error(err),
+		)
+		// The canonical number may already belong to another shipment; keep
+		// tracking this one under the number it was saved with.
+		sh.TrackingNumber = number
+		return
+	}
+
+	w.logger.Info("Shipment carrier detected",
+		zap.String("tracking_number", sh.TrackingNumber),
+		zap.String("entered_tracking_number", number),
+		zap.String("carrier", carrier.Key),
+	)
+}
+
+// markInvalid records a permanent carrier error on the shipment and moves it to
+// the final invalid status so it is no longer polled.
+func (w *Worker) markInvalid(ctx context.Context, sh *models.Shipment, cause error) {
+	previous := *sh
+
+	status, err := w.repo.GetStatus("invalid")
+	if err != nil {
+		w.logger.Error("Failed to get shipment status",
+			zap.String("tracking_number", sh.TrackingNumber),
+			zap.String("status_key", "invalid"),
+			zap.Error(err),
+		)
+		return
+	}
+
+	now := time.Now().UTC()
+	sh.Status = &status
+	sh.LastError = cause.Error()
+	sh.LastErrorAt = &now
+	sh.LastCheckedAt = &now
+
+	if err := w.saveShipment(ctx, previous, sh, nil); err != nil {
+		w.logger.Error("Failed to save shipment",
+			zap.String("tracking_number", sh.TrackingNumber),
+			zap.Error(err),
+		)
+		return
+	}
+
+	w.logger.Warn("Shipment marked invalid after permanent carrier error",
+		zap.String("tracking_number", sh.TrackingNumber),
+		zap.Error(cause),
+	)
+}
+
+// deferShipment pushes the next check of a shipment back until its carrier
+// has requests to spare, without counting a failure against it.
+func (w *Worker) deferShipment(ctx context.Context, sh *models.Shipment, until time.Time) {
+	previous := *sh
+	next := until.UTC()
+	sh.NextCheckAt = &next
+
+	if err := w.saveShipment(ctx, previous, sh, nil); err != nil {
+		w.logger.Error("Failed to save shipment",
+			zap.String("tracking_number", sh.TrackingNumber),
+			zap.Error(err),
+		)
+		return
+	}
+
+	w.logger.Info("Carrier rate limit reached, shipment deferred",
+		zap.String("tracking_number", sh.TrackingNumber),
+		zap.String("carrier", carrierKey(*sh)),
+		zap.Time("next_check_at", next),
+	)
+}
+
+// recordFailure tracks a failed check on the shipment and pushes its next
+// check back exponentially. After too many failures in a row the shipment is
+// moved to the error status.
+func (w *Worker) recordFailure(ctx context.Context, sh *models.Shipment, cause error) {
+	previous := *sh
+	now := time.Now().UTC()
+	sh.ConsecutiveFailures++
+	sh.LastError = cause.Error()
+	sh.LastErrorAt = &now
+
+	next := now.Add(core.Backoff(sh.ConsecutiveFailures, w.cfg.Shipments.FailureBaseDelay, w.cfg.Shipments.FailureMaxDelay))
+	sh.NextCheckAt = &next
+
+	if sh.ConsecutiveFailures >= w.cfg.Shipments.MaxFailures && (sh.Status == nil || sh.Status.Key != "error") {
+		status, err := w.repo.GetStatus("error")
+		if err != nil {
+			w.logger.Error("Failed to get shipment status",
+				zap.String("tracking_number", sh.TrackingNumber),
+				zap.String("status_key", "error"),
+				zap.Error(err),
+			)
+		} else {
+			sh.Status = &status
+			w.logger.Warn("Shipment moved to error status",
+				zap.String("tracking_number", sh.TrackingNumber),
+				zap.Int("consecutive_failures", sh.ConsecutiveFailures),
+			)
+		}
+	}
+
+	if err := w.saveShipment(ctx, previous, sh, nil); err != nil {
+		w.logger.Error("Failed to save shipment",
+			zap.String("tracking_number", sh.TrackingNumber),
+			zap.Error(err),
+		)
+	}
+}
+
+// saveShipment saves the shipment with any new events and emits an event for
+// every difference from its state before this check. Transactional
+// subscribers see the changes inside the save's transaction, so their writes
+// commit with it; everyone else hears about them once it has committed.
+func (w *Worker) saveShipment(ctx context.Context, before models.Shipment, sh *models.Shipment, events []models.ShipmentEvent) error {
+	var changes []core.Event
+	if w.events != nil {
+		changes = diffShipment(before, *sh)
+	}
+
+	err := w.repo.SaveShipmentWithEvents(sh, events, func(tx *gorm.DB) error {
+		for _, event := range changes {
+			if err := w.events.PublishTx(ctx, tx, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range changes {
+		w.events.Publish(ctx, event)
+	}
+	return nil
 }
 
 func (w *Worker) updateShipmentFromResult(sh *models.Shipment, result *processors.CarrierTrackingResults, status *models.ShipmentStatus) {
 	sh.Status = status
 	sh.LastLocation = result.LastLocation
+	sh.LastError = ""
+	sh.LastErrorAt = nil
+	sh.ConsecutiveFailures = 0
+	sh.NextCheckAt = nil
+
+	if result.HandoffTrackingNumber != "" {
+		sh.HandoffTrackingNumber = result.HandoffTrackingNumber
+	}
 
 	if result.LastCheckedAt != nil {
 		utc := result.LastCheckedAt.UTC()
@@ -174,25 +564,132 @@ func (w *Worker) updateShipmentFromResult(sh *models.Shipment, result *processor
 	}
 }
 
-func (w *Worker) getProcessor(carrier string) processors.CarrierTrackingProcessor {
+func (w *Worker) getProcessor(carrier string) (*processors.CircuitBreaker, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if breaker, exists := w.processors[carrier]; exists {
+		return breaker, nil
+	}
+
+	processor, err := w.registry.New(carrier, w.cfg, w.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	breaker := processors.NewCircuitBreaker(carrier, processor, w.cfg.Shipments.BreakerThreshold, w.cfg.Shipments.BreakerCooldown, w.logger)
+
+	w.processors[carrier] = breaker
+	return breaker, nil
+}
+
+// Carriers reports which registered carriers have valid configuration.
+func (w *Worker) Carriers() ([]processors.CarrierReport, error) {
+	if err := w.refreshRegistry(); err != nil {
+		return nil, err
+	}
+
 	w.mu.Lock()
 	defer w.mu.Unlock()
+	return w.registry.Report(w.cfg), nil
+}
 
-	if processor, exists := w.processors[carrier]; exists {
-		return processor
+// refreshRegistry reloads the carrier scrape definitions once they are older
+// than scrapeDefinitionsTTL and rebuilds the registry if they changed. Until
+// definitions have been loaded once, failing to load them is an error; after
+// that a failed reload keeps the last ones loaded and is retried next time.
+func (w *Worker) refreshRegistry() error {
+	w.refreshMu.Lock()
+	defer w.refreshMu.Unlock()
+
+	if w.registry != nil && time.Since(w.loadedAt) < scrapeDefinitionsTTL {
+		return nil
 	}
 
-	var processor processors.CarrierTrackingProcessor
+	definitions, err := w.repo.GetScrapeDefinitions()
+	if err != nil {
+		if w.registry == nil {
+			return fmt.Errorf("failed to load carrier scrape definitions: %w", err)
+		}
+		w.logger.Error("Failed to reload carrier scrape definitions, keeping the previous ones", zap.Error(err))
+		return nil
+	}
+	w.loadedAt = time.Now()
+
+	if w.registry != nil && reflect.DeepEqual(definitions, w.definitions) {
+		return nil
+	}
+
+	registry := NewRegistry(definitions, w.statuses)
+
+	w.mu.Lock()
+	// Processors built from an old definition are dropped; every other
+	// carrier keeps its processor and circuit breaker.
+	for _, def := range slices.Concat(w.definitions, definitions) {
+		if def.Carrier != nil {
+			delete(w.processors, def.Carrier.Key)
+		}
+	}
+	w.registry = registry
+	w.mu.Unlock()
+
+	w.definitions = definitions
+	w.reportCarriers(registry)
+	return nil
+}
+
+func (w *Worker) reportCarriers(registry *processors.Registry) {
+	for _, report := range registry.Report(w.cfg) {
+		if report.Configured {
+			w.logger.Info("Carrier configured", zap.String("carrier", report.Key))
+		} else {
+			w.logger.Warn("Carrier not configured",
+				zap.String("carrier", report.Key),
+				zap.String("details", report.Error),
+			)
+		}
+	}
+}
+
+func toShipmentEvents(events []processors.CarrierTrackingEvent) []models.ShipmentEvent {
+	ret := make([]models.ShipmentEvent, 0, len(events))
+	for _, e := range events {
+		_, offset := e.OccurredAt.Zone()
+		ret = append(ret, models.ShipmentEvent{
+			OccurredAt:            e.OccurredAt.UTC(),
+			OccurredOffsetSeconds: &offset,
+			CarrierStatusCode:     e.CarrierStatusCode,
+			Location:              e.Location,
+			Description:           e.Description,
+			Status:                e.Status,
+		})
+	}
+	return ret
+}
+
+func carrierKey(sh models.Shipment) string {
+	if sh.Carrier == nil {
+		return ""
+	}
+	return sh.Carrier.Key
+}
+
+// getLimiter returns the shared rate limiter for a carrier so that quotas hold
+// across every shipment in a run, regardless of pool size.
+func (w *Worker) getLimiter(carrier string) *rate.Limiter {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if limiter, exists := w.limiters[carrier]; exists {
+		return limiter
+	}
 
-	switch carrier {
-	case "ups":
-		processor = ups.NewTrackingProcessor(w.logger)
-	case "uds":
-		processor = uds.NewTrackingProcessor(w.logger)
-	default:
-		processor = unsupported.NewTrackingProcessor(w.logger)
+	limit, ok := w.cfg.Shipments.RateLimits[carrier]
+	if !ok {
+		limit = w.cfg.Shipments.DefaultRateLimit
 	}
 
-	w.processors[carrier] = processor
-	return processor
+	limiter := rate.NewLimiter(rate.Limit(limit.PerSecond), limit.Burst)
+	w.limiters[carrier] = limiter
+	return limiter
 }
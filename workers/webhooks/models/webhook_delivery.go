@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// WebhookDelivery represents webhook_deliveries table, the outbox of
+// webhook calls still to be made or already made.
+type WebhookDelivery struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement"`
+	URL           string    `gorm:"size:512;not null"`
+	Event         string    `gorm:"size:100;not null"`
+	Payload       string    `gorm:"type:jsonb;not null"`
+	Status        string    `gorm:"size:20;not null"`
+	Attempts      int       `gorm:"not null;default:0"`
+	NextAttemptAt time.Time `gorm:"not null"`
+	LastError     string    `gorm:"type:text"`
+	CreatedAt     time.Time `gorm:"not null"`
+	DeliveredAt   *time.Time
+}
@@ -0,0 +1,106 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"fmt"
+	"gorm.io/gorm"
+	"personal-homepage-service/config"
+	"personal-homepage-service/workers/shipments"
+	"personal-homepage-service/workers/webhooks/models"
+	"personal-homepage-service/workers/webhooks/repositories"
+	"time"
+)
+
+const EventShipmentStatusChanged = "shipment.status_changed"
+
+// Payload is the JSON body POSTed to every webhook URL.
+type Payload struct {
+	Event     string          `json:"event"`
+	CreatedAt time.Time       `json:"created_at"`
+	Shipment  ShipmentPayload `json:"shipment"`
+}
+
+type ShipmentPayload struct {
+	ID                  uint       `json:"id"`
+	Label               string     `json:"label"`
+	TrackingNumber      string     `json:"tracking_number"`
+	TrackingURL         string     `json:"tracking_url,omitempty"`
+	Carrier             string     `json:"carrier,omitempty"`
+	PreviousStatus      string     `json:"previous_status,omitempty"`
+	Status              string     `json:"status"`
+	LastLocation        string     `json:"last_location,omitempty"`
+	DeliveryWindowStart *time.Time `json:"delivery_window_start,omitempty"`
+	DeliveryWindowEnd   *time.Time `json:"delivery_window_end,omitempty"`
+}
+
+// Outbox queues webhook deliveries in the database, inside the transaction
+// that saved the change they describe. Deliveries are sent by the Worker, so a
+// slow or failing receiver never holds up shipment processing.
+type Outbox struct {
+	cfg *config.Config
+}
+
+func NewOutbox(cfg config.Config) *Outbox {
+	return &Outbox{cfg: &cfg}
+}
+
+// Enabled reports whether webhook URLs and the secret to sign deliveries with
+// are both configured. Deliveries are never sent unsigned.
+func (o *Outbox) Enabled() bool {
+	return len(o.cfg.Webhooks.URLs) > 0 && o.cfg.Webhooks.Secret != ""
+}
+
+// EnqueueStatusChanged queues a delivery of the status change to every
+// configured URL within tx.
+func (o *Outbox) EnqueueStatusChanged(tx *gorm.DB, e shipments.StatusChanged) error {
+	sh := e.Shipment
+	payload := Payload{
+		Event:     EventShipmentStatusChanged,
+		CreatedAt: time.Now().UTC(),
+		Shipment: ShipmentPayload{
+			ID:                  sh.ID,
+			Label:               sh.Label,
+			TrackingNumber:      sh.TrackingNumber,
+			TrackingURL:         sh.TrackingURL,
+			PreviousStatus:      e.PreviousStatus,
+			Status:              e.Status,
+			LastLocation:        sh.LastLocation,
+			DeliveryWindowStart: sh.DeliveryWindowStart,
+			DeliveryWindowEnd:   sh.DeliveryWindowEnd,
+		},
+	}
+	if sh.Carrier != nil {
+		payload.Shipment.Carrier = sh.Carrier.Key
+	}
+
+	return o.enqueue(tx, payload.Event, payload)
+}
+
+func (o *Outbox) enqueue(tx *gorm.DB, event string, payload any) error {
+	if !o.Enabled() {
+		return nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]models.WebhookDelivery, 0, len(o.cfg.Webhooks.URLs))
+	for _, url := range o.cfg.Webhooks.URLs {
+		deliveries = append(deliveries, models.WebhookDelivery{
+			URL:           url,
+			Event:         event,
+			Payload:       string(body),
+			Status:        models.DeliveryStatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+	}
+
+	if err := repositories.NewRepository(tx).CreateDeliveries(deliveries); err != nil {
+		return fmt.Errorf("failed to queue webhook deliveries: %w", err)
+	}
+	return nil
+}
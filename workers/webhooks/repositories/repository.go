@@ -0,0 +1,34 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+	"personal-homepage-service/workers/webhooks/models"
+	"time"
+)
+
+type Repository struct {
+	db *gorm.DB
+}
+
+func NewRepository(db *gorm.DB) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	return r.db.Create(&deliveries).Error
+}
+
+// GetDueDeliveries returns pending deliveries whose next attempt is due,
+// oldest first.
+func (r *Repository) GetDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryStatusPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *Repository) SaveDelivery(delivery *models.WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
@@ -0,0 +1,57 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// Sign returns the signature header value for body, in the form
+// "t=<unix timestamp>,v1=<hex hmac>". The MAC covers "<timestamp>.<body>" so a
+// receiver can reject replays of old deliveries.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, hex.EncodeToString(mac(secret, ts, body)))
+}
+
+// Verify reports whether header is a valid signature of body made with secret.
+func Verify(secret string, header string, body []byte) bool {
+	ts, sig, ok := strings.Cut(header, ",")
+	if !ok {
+		return false
+	}
+
+	ts, ok = strings.CutPrefix(ts, "t=")
+	if !ok {
+		return false
+	}
+
+	sig, ok = strings.CutPrefix(sig, "v1=")
+	if !ok {
+		return false
+	}
+
+	expected, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal(expected, mac(secret, ts, body))
+}
+
+func mac(secret string, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
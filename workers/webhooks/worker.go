@@ -0,0 +1,163 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"personal-homepage-service/config"
+	"personal-homepage-service/core"
+	"personal-homepage-service/workers/webhooks/models"
+	"personal-homepage-service/workers/webhooks/repositories"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	batchSize      = 100
+	requestTimeout = 10 * time.Second
+	retryBaseDelay = 30 * time.Second
+	retryMaxDelay  = 6 * time.Hour
+)
+
+// Worker sends queued webhook deliveries, retrying failed ones with backoff
+// until they succeed or run out of attempts.
+type Worker struct {
+	logger *zap.Logger
+	cfg    *config.Config
+	repo   *repositories.Repository
+	client *http.Client
+	busy   atomic.Bool
+}
+
+func NewWorker(logger *zap.Logger, cfg config.Config, db *gorm.DB) *Worker {
+	if len(cfg.Webhooks.URLs) > 0 && cfg.Webhooks.Secret == "" {
+		logger.Error("WEBHOOK_SECRET is not set, webhooks are disabled")
+	}
+
+	return &Worker{
+		logger: logger,
+		cfg:    &cfg,
+		repo:   repositories.NewRepository(db),
+		client: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+func (w *Worker) Name() string {
+	return "webhooks"
+}
+
+func (w *Worker) Schedule() string {
+	return "* * * * *"
+}
+
+func (w *Worker) Ready(time.Time) bool {
+	return !w.busy.Load()
+}
+
+func (w *Worker) Execute(ctx context.Context) error {
+	if !w.busy.CompareAndSwap(false, true) {
+		return core.ErrWorkerBusy
+	}
+	defer w.busy.Store(false)
+
+	// Deliveries queued before the secret was removed wait for it to be set
+	// again rather than go out unsigned.
+	if w.cfg.Webhooks.Secret == "" {
+		return nil
+	}
+
+	deliveries, err := w.repo.GetDueDeliveries(time.Now().UTC(), batchSize)
+	if err != nil {
+		return fmt.Errorf("failed to load webhook deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		w.deliver(ctx, &delivery)
+	}
+
+	return nil
+}
+
+func (w *Worker) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	counters := core.CountersFromContext(ctx)
+	now := time.Now().UTC()
+	delivery.Attempts++
+
+	err := w.send(ctx, delivery)
+	if err != nil && ctx.Err() != nil {
+		// Leave the delivery untouched so the next run picks it up again.
+		return
+	}
+
+	switch {
+	case err == nil:
+		delivery.Status = models.DeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		counters.Add("webhooks_delivered", 1)
+	case delivery.Attempts >= w.cfg.Webhooks.MaxAttempts:
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.LastError = err.Error()
+		counters.Add("webhooks_failed", 1)
+		w.logger.Error("Webhook delivery failed, giving up",
+			zap.Uint("delivery_id", delivery.ID),
+			zap.String("url", delivery.URL),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Error(err),
+		)
+	default:
+		delivery.NextAttemptAt = now.Add(core.Backoff(delivery.Attempts, retryBaseDelay, retryMaxDelay))
+		delivery.LastError = err.Error()
+		counters.Add("webhooks_retried", 1)
+		w.logger.Warn("Webhook delivery failed, will retry",
+			zap.Uint("delivery_id", delivery.ID),
+			zap.String("url", delivery.URL),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Time("next_attempt_at", delivery.NextAttemptAt),
+			zap.Error(err),
+		)
+	}
+
+	if err := w.repo.SaveDelivery(delivery); err != nil {
+		w.logger.Error("Failed to save webhook delivery",
+			zap.Uint("delivery_id", delivery.ID),
+			zap.Error(err),
+		)
+	}
+}
+
+func (w *Worker) send(ctx context.Context, delivery *models.WebhookDelivery) error {
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create webhook request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(SignatureHeader, Sign(w.cfg.Webhooks.Secret, time.Now(), body))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("webhook returned status %d: %s", res.StatusCode, snippet)
+	}
+
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}